@@ -0,0 +1,81 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+)
+
+// TypeStoreAPI resources report whether the last request made to the pet
+// store on their behalf succeeded, and if not why.
+const TypeStoreAPI xpv1.ConditionType = "StoreAPI"
+
+// Reasons the last request made to the pet store did or did not succeed.
+const (
+	ReasonRequestSucceeded xpv1.ConditionReason = "RequestSucceeded"
+	ReasonInvalidSpec      xpv1.ConditionReason = "InvalidSpec"
+	ReasonAccessDenied     xpv1.ConditionReason = "AccessDenied"
+	ReasonConflict         xpv1.ConditionReason = "Conflict"
+	ReasonThrottled        xpv1.ConditionReason = "Throttled"
+	ReasonStoreError       xpv1.ConditionReason = "StoreError"
+	ReasonRequestFailed    xpv1.ConditionReason = "RequestFailed"
+	ReasonTLSError         xpv1.ConditionReason = "TLSError"
+
+	// ReasonInvalidResponse indicates that the pet store answered, but its
+	// response could not be decoded.
+	ReasonInvalidResponse xpv1.ConditionReason = "InvalidResponse"
+
+	// ReasonStoreUnavailable indicates that no request was made because
+	// the pet store kept failing, and its circuit breaker is open.
+	ReasonStoreUnavailable xpv1.ConditionReason = "StoreUnavailable"
+
+	// ReasonCredentialsUnavailable indicates that no request could be made
+	// because the credentials of the ProviderConfig are missing or could not
+	// be read.
+	ReasonCredentialsUnavailable xpv1.ConditionReason = "CredentialsUnavailable"
+
+	// ReasonInvalidProviderConfig indicates that no request could be made
+	// because the ProviderConfig is invalid, for example because its proxy
+	// URL cannot be parsed.
+	ReasonInvalidProviderConfig xpv1.ConditionReason = "InvalidProviderConfig"
+)
+
+// StoreAPISucceeded returns a condition indicating that the last request made
+// to the pet store succeeded.
+func StoreAPISucceeded() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeStoreAPI,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonRequestSucceeded,
+	}
+}
+
+// StoreAPIFailed returns a condition indicating that the last request made to
+// the pet store failed for the supplied reason.
+func StoreAPIFailed(reason xpv1.ConditionReason, err error) xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeStoreAPI,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             reason,
+		Message:            err.Error(),
+	}
+}
@@ -23,6 +23,8 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+
+	apisv1alpha1 "github.com/alexisries/provider-petstore/apis/v1alpha1"
 )
 
 type PetCategory struct {
@@ -58,6 +60,46 @@ type PetParameters struct {
 	// List of pet photos url
 	// +optional
 	PhotoUrls []string `json:"photosUrls,omitempty"`
+
+	// Images of the pet, uploaded to the pet store. An image is uploaded
+	// again whenever its content or metadata change.
+	// +optional
+	Images []PetImage `json:"images,omitempty"`
+}
+
+// A PetImage is an image of a pet, uploaded to the pet store.
+type PetImage struct {
+	// Name identifies the image among the images of the pet. It is also the
+	// file name the image is uploaded with.
+	Name string `json:"name"`
+
+	// Source of the content of the image.
+	Source PetImageSource `json:"source"`
+
+	// AdditionalMetadata is sent to the pet store along with the image.
+	// +optional
+	AdditionalMetadata string `json:"additionalMetadata,omitempty"`
+}
+
+// A PetImageSource selects the content of an image. Exactly one of its
+// fields must be set.
+type PetImageSource struct {
+	// ConfigMapKeyRef selects a key of the binaryData of a ConfigMap. Its
+	// namespace must be one of the image source namespaces of the
+	// ProviderConfig.
+	// +optional
+	ConfigMapKeyRef *apisv1alpha1.ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+
+	// SecretKeyRef selects a key of a Secret. Its namespace must be one of
+	// the image source namespaces of the ProviderConfig.
+	// +optional
+	SecretKeyRef *xpv1.SecretKeySelector `json:"secretKeyRef,omitempty"`
+
+	// Path of a file mounted into the provider, relative to the image
+	// directory of the ProviderConfig. It may not resolve outside of it,
+	// even through symbolic links.
+	// +optional
+	Path string `json:"path,omitempty"`
 }
 
 // PetObservation keeps the state of external resource
@@ -68,6 +110,35 @@ type PetObservation struct {
 	// Status of the pet
 	// +kubebuilder:validation:Enum=AVAILABLE;INPROGRESS;INACTIVE;PENDING;FAILED
 	Status string `json:"status,omitempty"`
+
+	// Images uploaded to the pet store.
+	// +optional
+	Images []PetImageObservation `json:"images,omitempty"`
+
+	// ManagedPhotoUrls are the photo URLs last applied to the pet. Those
+	// dropped from the parameters are removed from the pet, while photo URLs
+	// someone else added are kept.
+	// +optional
+	ManagedPhotoUrls []string `json:"managedPhotoUrls,omitempty"`
+
+	// ManagedTagIds are the IDs of the tags last applied to the pet. Those
+	// dropped from the parameters are removed from the pet, while tags
+	// someone else added are kept.
+	// +optional
+	ManagedTagIds []int64 `json:"managedTagIds,omitempty"`
+}
+
+// A PetImageObservation records an image uploaded to the pet store.
+type PetImageObservation struct {
+	// Name of the image.
+	Name string `json:"name"`
+
+	// SHA256 is the hex encoded SHA-256 hash of the uploaded content.
+	SHA256 string `json:"sha256"`
+
+	// AdditionalMetadata uploaded along with the image.
+	// +optional
+	AdditionalMetadata string `json:"additionalMetadata,omitempty"`
 }
 
 // A PetSpec defines the desired state of a Pet.
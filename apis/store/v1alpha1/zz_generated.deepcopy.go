@@ -22,6 +22,8 @@ limitations under the License.
 package v1alpha1
 
 import (
+	apisv1alpha1 "github.com/alexisries/provider-petstore/apis/v1alpha1"
+	"github.com/crossplane/crossplane-runtime/apis/common/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -67,6 +69,62 @@ func (in *PetCategory) DeepCopy() *PetCategory {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PetImage) DeepCopyInto(out *PetImage) {
+	*out = *in
+	in.Source.DeepCopyInto(&out.Source)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PetImage.
+func (in *PetImage) DeepCopy() *PetImage {
+	if in == nil {
+		return nil
+	}
+	out := new(PetImage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PetImageObservation) DeepCopyInto(out *PetImageObservation) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PetImageObservation.
+func (in *PetImageObservation) DeepCopy() *PetImageObservation {
+	if in == nil {
+		return nil
+	}
+	out := new(PetImageObservation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PetImageSource) DeepCopyInto(out *PetImageSource) {
+	*out = *in
+	if in.ConfigMapKeyRef != nil {
+		in, out := &in.ConfigMapKeyRef, &out.ConfigMapKeyRef
+		*out = new(apisv1alpha1.ConfigMapKeySelector)
+		**out = **in
+	}
+	if in.SecretKeyRef != nil {
+		in, out := &in.SecretKeyRef, &out.SecretKeyRef
+		*out = new(v1.SecretKeySelector)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PetImageSource.
+func (in *PetImageSource) DeepCopy() *PetImageSource {
+	if in == nil {
+		return nil
+	}
+	out := new(PetImageSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PetList) DeepCopyInto(out *PetList) {
 	*out = *in
@@ -102,6 +160,21 @@ func (in *PetList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PetObservation) DeepCopyInto(out *PetObservation) {
 	*out = *in
+	if in.Images != nil {
+		in, out := &in.Images, &out.Images
+		*out = make([]PetImageObservation, len(*in))
+		copy(*out, *in)
+	}
+	if in.ManagedPhotoUrls != nil {
+		in, out := &in.ManagedPhotoUrls, &out.ManagedPhotoUrls
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ManagedTagIds != nil {
+		in, out := &in.ManagedTagIds, &out.ManagedTagIds
+		*out = make([]int64, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PetObservation.
@@ -132,6 +205,13 @@ func (in *PetParameters) DeepCopyInto(out *PetParameters) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Images != nil {
+		in, out := &in.Images, &out.Images
+		*out = make([]PetImage, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PetParameters.
@@ -165,7 +245,7 @@ func (in *PetSpec) DeepCopy() *PetSpec {
 func (in *PetStatus) DeepCopyInto(out *PetStatus) {
 	*out = *in
 	in.ResourceStatus.DeepCopyInto(&out.ResourceStatus)
-	out.AtProvider = in.AtProvider
+	in.AtProvider.DeepCopyInto(&out.AtProvider)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PetStatus.
@@ -0,0 +1,92 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+)
+
+// TypeStoreAvailable ProviderConfigs report whether the pet store they point
+// to is reachable, or whether requests to it are failed fast because it kept
+// failing.
+const TypeStoreAvailable xpv1.ConditionType = "StoreAvailable"
+
+// Reasons the pet store of a ProviderConfig is or is not available.
+const (
+	ReasonCircuitClosed   xpv1.ConditionReason = "CircuitClosed"
+	ReasonCircuitOpen     xpv1.ConditionReason = "CircuitOpen"
+	ReasonCircuitHalfOpen xpv1.ConditionReason = "CircuitHalfOpen"
+)
+
+// StoreAvailable returns a condition indicating that requests are sent to
+// the pet store.
+func StoreAvailable() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeStoreAvailable,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonCircuitClosed,
+	}
+}
+
+// StoreUnavailable returns a condition indicating that requests to the pet
+// store are failed fast for the supplied reason, because it kept failing.
+func StoreUnavailable(reason xpv1.ConditionReason, msg string) xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeStoreAvailable,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             reason,
+		Message:            msg,
+	}
+}
+
+// TypeConfigValid ProviderConfigs report whether their spec can be used to
+// reach the pet store.
+const TypeConfigValid xpv1.ConditionType = "ConfigValid"
+
+// Reasons the spec of a ProviderConfig is or is not valid.
+const (
+	ReasonValidConfig xpv1.ConditionReason = "Valid"
+	ReasonInvalidURL  xpv1.ConditionReason = "InvalidURL"
+)
+
+// ConfigValid returns a condition indicating that the spec of a
+// ProviderConfig is valid.
+func ConfigValid() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeConfigValid,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonValidConfig,
+	}
+}
+
+// ConfigInvalid returns a condition indicating that the spec of a
+// ProviderConfig is invalid for the supplied reason.
+func ConfigInvalid(reason xpv1.ConditionReason, msg string) xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeConfigValid,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             reason,
+		Message:            msg,
+	}
+}
@@ -29,7 +29,285 @@ import (
 type ProviderConfigSpec struct {
 	// Credentials required to authenticate to this provider.
 	Credentials ProviderCredentials `json:"credentials"`
-	ServerUrl   string              `json:"url"`
+
+	// ServerUrl is the URL of the pet store, including its base path, for
+	// example https://petstore.swagger.io/v2. It must use the http or https
+	// scheme, and have no query.
+	ServerUrl string `json:"url"`
+
+	// Transport tunes the HTTP connections used to reach the pet store.
+	// +optional
+	Transport *TransportConfig `json:"transport,omitempty"`
+
+	// Retry controls how requests that fail transiently are retried.
+	// +optional
+	Retry *RetryPolicy `json:"retry,omitempty"`
+
+	// OAuth2 authenticates requests with an access token obtained through
+	// the OAuth2 client credentials flow, as described by the petstore_auth
+	// security scheme. It may be combined with an API key.
+	// +optional
+	OAuth2 *OAuth2Config `json:"oauth2,omitempty"`
+
+	// TLS configures how the pet store endpoint is authenticated, and how
+	// the provider authenticates to it.
+	// +optional
+	TLS *TLSConfig `json:"tls,omitempty"`
+
+	// Proxy routes requests to the pet store through an HTTP proxy. When it
+	// is not set, the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment
+	// variables of the provider apply.
+	// +optional
+	Proxy *ProxyConfig `json:"proxy,omitempty"`
+
+	// Headers are sent with every request to the pet store, for example to
+	// select a tenant behind an API gateway. They cannot override the
+	// headers set by the provider itself, such as the API key.
+	// +optional
+	Headers map[string]HeaderValue `json:"headers,omitempty"`
+
+	// RateLimit bounds the rate and concurrency of the requests sent to the
+	// pet store on behalf of all the resources using this ProviderConfig.
+	// Requests over the limits wait until they may be sent.
+	// +optional
+	RateLimit *RateLimitConfig `json:"rateLimit,omitempty"`
+
+	// ObservationCache caches the pets observed in the pet store on behalf
+	// of all the resources using this ProviderConfig. Pets are revalidated
+	// with conditional requests whenever the store returns an ETag or a
+	// Last-Modified header.
+	// +optional
+	ObservationCache *ObservationCacheConfig `json:"observationCache,omitempty"`
+
+	// Format is the media type of the bodies exchanged with the pet store.
+	// JSON and XML select application/json and application/xml. Auto
+	// accepts both, decodes responses according to their Content-Type, and
+	// sends request bodies as JSON. Defaults to JSON.
+	// +kubebuilder:validation:Enum=JSON;XML;Auto
+	// +optional
+	Format string `json:"format,omitempty"`
+
+	// Decoding controls how the responses of the pet store are decoded.
+	// +optional
+	Decoding *DecodingConfig `json:"decoding,omitempty"`
+
+	// IDStrategy determines who picks the IDs of new pets. With Server the
+	// pet store assigns them, and they are read from its responses; new pets
+	// are given a marker tag derived from the UID of their Pet, which is
+	// recorded before creating them and removed once they are. With Client
+	// the provider picks random IDs no pet has yet, and records them before
+	// creating the pets. Either way, a pet whose creation had an unknown
+	// outcome is found rather than created again. Defaults to Server.
+	// +kubebuilder:validation:Enum=Server;Client
+	// +optional
+	IDStrategy string `json:"idStrategy,omitempty"`
+
+	// ImageDirectory is the directory of the provider that the paths of pet
+	// images are resolved in, for example a mounted volume. Images cannot be
+	// read from paths unless it is set.
+	// +optional
+	ImageDirectory string `json:"imageDirectory,omitempty"`
+
+	// ImageSourceNamespaces are the namespaces of the Secrets and ConfigMaps
+	// that pet images may be read from. Images cannot be read from Secrets or
+	// ConfigMaps of other namespaces, since anyone allowed to create a Pet
+	// could otherwise upload any of those the provider can read to the pet
+	// store.
+	// +optional
+	ImageSourceNamespaces []string `json:"imageSourceNamespaces,omitempty"`
+}
+
+// DecodingConfig controls how the responses of the pet store are decoded.
+type DecodingConfig struct {
+	// MaxResponseBytes bounds the size of the bodies of the responses of the
+	// pet store. Larger responses fail to decode. Defaults to 1048576.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxResponseBytes *int64 `json:"maxResponseBytes,omitempty"`
+
+	// Strict reports the fields of JSON responses that the provider does not
+	// know as warning events on the resources they were received for, for
+	// example because the pet store runs a newer version of its API.
+	// +optional
+	Strict bool `json:"strict,omitempty"`
+}
+
+// ObservationCacheConfig configures the cache of observed pets.
+type ObservationCacheConfig struct {
+	// TTL is how long an observed pet is reused without contacting the pet
+	// store. Changes made outside of the provider may go unnoticed for as
+	// long. Writes made by the provider invalidate the cached pet at once.
+	// Defaults to 0, which revalidates every observation.
+	// +optional
+	TTL *metav1.Duration `json:"ttl,omitempty"`
+}
+
+// RateLimitConfig bounds the requests sent to the pet store.
+type RateLimitConfig struct {
+	// RequestsPerSecond is the sustained rate of requests. Zero means no
+	// limit.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	RequestsPerSecond *int `json:"requestsPerSecond,omitempty"`
+
+	// Burst is the number of requests that may be sent at once above the
+	// sustained rate. Defaults to RequestsPerSecond.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Burst *int `json:"burst,omitempty"`
+
+	// MaxInFlight is the maximum number of requests awaiting a response
+	// from the pet store. Zero means no limit.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxInFlight *int `json:"maxInFlight,omitempty"`
+}
+
+// ProxyConfig configures the HTTP proxy used to reach the pet store.
+type ProxyConfig struct {
+	// URL of the proxy, for example http://proxy.example.com:3128.
+	URL string `json:"url"`
+
+	// NoProxy lists the hosts reached without the proxy. An entry may be a
+	// host name, which also matches its subdomains, a domain prefixed with
+	// a dot, an IP address, a CIDR range or "*". Host names and IP
+	// addresses may be followed by a port.
+	// +optional
+	NoProxy []string `json:"noProxy,omitempty"`
+}
+
+// A HeaderValue is either a literal value or a reference to the key of a
+// Secret holding the value.
+type HeaderValue struct {
+	// Value of the header.
+	// +optional
+	Value string `json:"value,omitempty"`
+
+	// SecretKeyRef selects the key of a Secret holding the value of the
+	// header, for headers carrying credentials.
+	// +optional
+	SecretKeyRef *xpv1.SecretKeySelector `json:"secretKeyRef,omitempty"`
+}
+
+// TLSConfig configures TLS connections to the pet store. Certificates and
+// keys are PEM encoded.
+type TLSConfig struct {
+	// CABundle references certificate authorities trusted to verify the
+	// certificate of the pet store, in addition to the system roots.
+	// +optional
+	CABundle *KeySelector `json:"caBundle,omitempty"`
+
+	// ClientCert references the client certificate presented to pet stores
+	// requiring mutual TLS. It must be set together with ClientKey.
+	// +optional
+	ClientCert *KeySelector `json:"clientCert,omitempty"`
+
+	// ClientKey references the private key of ClientCert. It should be
+	// stored in a Secret.
+	// +optional
+	ClientKey *KeySelector `json:"clientKey,omitempty"`
+
+	// ServerName overrides the name used to verify the certificate of the
+	// pet store.
+	// +optional
+	ServerName string `json:"serverName,omitempty"`
+}
+
+// A KeySelector selects a key of either a Secret or a ConfigMap.
+type KeySelector struct {
+	// SecretKeyRef selects a key of a Secret.
+	// +optional
+	SecretKeyRef *xpv1.SecretKeySelector `json:"secretKeyRef,omitempty"`
+
+	// ConfigMapKeyRef selects a key of a ConfigMap.
+	// +optional
+	ConfigMapKeyRef *ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+}
+
+// A ConfigMapKeySelector is a reference to a key of a ConfigMap in an
+// arbitrary namespace.
+type ConfigMapKeySelector struct {
+	// Name of the ConfigMap.
+	Name string `json:"name"`
+
+	// Namespace of the ConfigMap.
+	Namespace string `json:"namespace"`
+
+	// The key to select.
+	Key string `json:"key"`
+}
+
+// OAuth2Config configures the OAuth2 client credentials flow.
+type OAuth2Config struct {
+	// TokenURL is the token endpoint of the authorization server.
+	TokenURL string `json:"tokenURL"`
+
+	// ClientID identifies the provider to the authorization server.
+	ClientID string `json:"clientID"`
+
+	// ClientSecretRef references the key of a Secret holding the client
+	// secret.
+	ClientSecretRef xpv1.SecretKeySelector `json:"clientSecretRef"`
+
+	// Scopes requested for the access token, for example write:pets and
+	// read:pets.
+	// +optional
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// TransportConfig tunes the HTTP client used to reach the pet store. Every
+// ProviderConfig gets its own connection pool.
+type TransportConfig struct {
+	// RequestTimeout bounds each request sent to the pet store, including
+	// reading the response body. Defaults to 30s.
+	// +optional
+	RequestTimeout *metav1.Duration `json:"requestTimeout,omitempty"`
+
+	// IdleConnTimeout is how long an idle connection is kept in the pool
+	// before being closed. Defaults to 90s.
+	// +optional
+	IdleConnTimeout *metav1.Duration `json:"idleConnTimeout,omitempty"`
+
+	// MaxIdleConns is the maximum number of idle connections kept in the
+	// pool. Defaults to 100.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxIdleConns *int `json:"maxIdleConns,omitempty"`
+
+	// KeepAlive is the interval between TCP keep-alive probes on open
+	// connections. Defaults to 30s.
+	// +optional
+	KeepAlive *metav1.Duration `json:"keepAlive,omitempty"`
+
+	// MaxConnsPerHost limits the total number of connections to the pet
+	// store, including those in use. Zero means no limit.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxConnsPerHost *int `json:"maxConnsPerHost,omitempty"`
+}
+
+// RetryPolicy controls how requests that fail transiently are retried, using
+// capped exponential backoff with jitter. Idempotent requests are retried on
+// network errors and on 408, 502, 503 and 504 responses. Any request is
+// retried on a 429 response. A Retry-After header sent by the pet store is
+// always honoured.
+type RetryPolicy struct {
+	// MaxRetries is the maximum number of times a request is retried. Set
+	// it to 0 to disable retries. Defaults to 3.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxRetries *int `json:"maxRetries,omitempty"`
+
+	// InitialBackoff is the upper bound of the wait before the first retry.
+	// It doubles on every subsequent retry. Defaults to 500ms.
+	// +optional
+	InitialBackoff *metav1.Duration `json:"initialBackoff,omitempty"`
+
+	// MaxBackoff caps the wait between two attempts. A request whose
+	// Retry-After header asks for a longer wait is not retried. Defaults to
+	// 30s.
+	// +optional
+	MaxBackoff *metav1.Duration `json:"maxBackoff,omitempty"`
 }
 
 // ProviderCredentials required to authenticate.
@@ -46,14 +46,19 @@ func main() {
 	var (
 		app            = kingpin.New(filepath.Base(os.Args[0]), "PetStore support for Crossplane.").DefaultEnvars()
 		debug          = app.Flag("debug", "Run with debug logging.").Short('d').Bool()
+		metricsAddr    = app.Flag("metrics-bind-address", "The address the metrics endpoint binds to. Set to 0 to disable serving metrics.").Default(":8080").Envar("METRICS_BIND_ADDRESS").String()
 		leaderElection = app.Flag("leader-election", "Use leader election for the controller manager.").Short('l').Default("false").OverrideDefaultFromEnvar("LEADER_ELECTION").Bool()
 
 		syncInterval     = app.Flag("sync", "How often all resources will be double-checked for drift from the desired state.").Short('s').Default("1h").Duration()
 		pollInterval     = app.Flag("poll", "How often individual resources will be checked for drift from the desired state").Default("1m").Duration()
 		maxReconcileRate = app.Flag("max-reconcile-rate", "The global maximum rate per second at which resources may checked for drift from the desired state.").Default("10").Int()
 
+		tracingExporter = app.Flag("tracing-exporter", "Where to send OpenTelemetry spans about reconciles and pet store requests.").Default(tracingExporterNone).Envar("TRACING_EXPORTER").Enum(tracingExporterNone, tracingExporterStdout, tracingExporterOTLP)
+		otlpEndpoint    = app.Flag("otlp-endpoint", "The address of the OTLP gRPC collector spans are sent to when the otlp tracing exporter is used.").Default("localhost:4317").Envar("OTLP_ENDPOINT").String()
+
 		namespace                  = app.Flag("namespace", "Namespace used to set as default scope in default secret store config.").Default("crossplane-system").Envar("POD_NAMESPACE").String()
 		enableExternalSecretStores = app.Flag("enable-external-secret-stores", "Enable support for ExternalSecretStores.").Default("false").Envar("ENABLE_EXTERNAL_SECRET_STORES").Bool()
+		dumpHTTPBodies             = app.Flag("dump-http-bodies", "Log the bodies of pet store requests and responses, with credentials redacted. Requires --debug.").Default("false").Envar("DUMP_HTTP_BODIES").Bool()
 	)
 	kingpin.MustParse(app.Parse(os.Args[1:]))
 
@@ -66,11 +71,15 @@ func main() {
 		ctrl.SetLogger(zl)
 	}
 
+	shutdownTracing, err := setupTracing(context.Background(), *tracingExporter, *otlpEndpoint)
+	kingpin.FatalIfError(err, "Cannot set up tracing")
+
 	cfg, err := ctrl.GetConfig()
 	kingpin.FatalIfError(err, "Cannot get API server rest config")
 
 	mgr, err := ctrl.NewManager(ratelimiter.LimitRESTConfig(cfg, *maxReconcileRate), ctrl.Options{
-		SyncPeriod: syncInterval,
+		SyncPeriod:         syncInterval,
+		MetricsBindAddress: *metricsAddr,
 
 		// controller-runtime uses both ConfigMaps and Leases for leader
 		// election by default. Leases expire after 15 seconds, with a
@@ -115,6 +124,14 @@ func main() {
 		})), "cannot create default store config")
 	}
 
+	if *dumpHTTPBodies {
+		o.Features.Enable(features.EnableHTTPBodyDump)
+		log.Info("Dumping pet store request and response bodies", "flag", features.EnableHTTPBodyDump)
+	}
+
 	kingpin.FatalIfError(petstore.Setup(mgr, o), "Cannot setup PetStore controllers")
-	kingpin.FatalIfError(mgr.Start(ctrl.SetupSignalHandler()), "Cannot start controller manager")
+	err = mgr.Start(ctrl.SetupSignalHandler())
+	// Flush the spans not exported yet before exiting.
+	_ = shutdownTracing(context.Background())
+	kingpin.FatalIfError(err, "Cannot start controller manager")
 }
@@ -0,0 +1,199 @@
+package petstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	defaultFailureThreshold = 5
+	defaultOpenTimeout      = 30 * time.Second
+)
+
+// BreakerOptions tune a CircuitBreaker. Zero values are replaced by sensible
+// defaults.
+type BreakerOptions struct {
+	// FailureThreshold is the number of consecutive failed requests after
+	// which the circuit opens.
+	FailureThreshold int
+	// OpenTimeout is how long the circuit stays open before a request is
+	// let through to probe whether the store recovered.
+	OpenTimeout time.Duration
+}
+
+func (o BreakerOptions) withDefaults() BreakerOptions {
+	if o.FailureThreshold <= 0 {
+		o.FailureThreshold = defaultFailureThreshold
+	}
+	if o.OpenTimeout <= 0 {
+		o.OpenTimeout = defaultOpenTimeout
+	}
+	return o
+}
+
+// A BreakerState is the state of a CircuitBreaker.
+type BreakerState string
+
+// States of a CircuitBreaker.
+const (
+	// BreakerClosed lets every request through.
+	BreakerClosed BreakerState = "Closed"
+	// BreakerOpen fails every request fast.
+	BreakerOpen BreakerState = "Open"
+	// BreakerHalfOpen lets a single probe request through, and fails the
+	// others fast until the probe completes.
+	BreakerHalfOpen BreakerState = "HalfOpen"
+)
+
+// A StoreUnavailableError is returned without contacting the pet store while
+// its circuit is open.
+type StoreUnavailableError struct {
+	Server string
+	// RetryAt is when a request will next be let through.
+	RetryAt time.Time
+}
+
+func (e *StoreUnavailableError) Error() string {
+	return fmt.Sprintf("StoreUnavailable: %s failed repeatedly, next attempt at %s", e.Server, e.RetryAt.Format(time.RFC3339))
+}
+
+func IsErrorStoreUnavailable(err error) bool {
+	var unavailable *StoreUnavailableError
+	return errors.As(err, &unavailable)
+}
+
+// A CircuitBreaker stops requests to a pet store that keeps failing, so that
+// a store that is down is not hammered by every resource using it.
+type CircuitBreaker struct {
+	server  string
+	options BreakerOptions
+	now     func() time.Time
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+}
+
+// NewCircuitBreaker returns a closed CircuitBreaker for the supplied server.
+func NewCircuitBreaker(server string, o BreakerOptions) *CircuitBreaker {
+	return &CircuitBreaker{
+		server:  server,
+		options: o.withDefaults(),
+		now:     time.Now,
+		state:   BreakerClosed,
+	}
+}
+
+// State returns the current state of the circuit. An open circuit whose
+// timeout expired is reported as half-open.
+func (b *CircuitBreaker) State() BreakerState {
+	if b == nil {
+		return BreakerClosed
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerOpen && !b.now().Before(b.retryAt()) {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+// allow returns a StoreUnavailableError if a request may not be sent. When
+// the open timeout expired, the calling request becomes the probe.
+func (b *CircuitBreaker) allow() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case BreakerClosed:
+		return nil
+	case BreakerOpen:
+		if b.now().Before(b.retryAt()) {
+			return &StoreUnavailableError{Server: b.server, RetryAt: b.retryAt()}
+		}
+		b.state = BreakerHalfOpen
+		return nil
+	}
+	// A probe is already in flight.
+	return &StoreUnavailableError{Server: b.server, RetryAt: b.now().Add(b.options.OpenTimeout)}
+}
+
+// record updates the circuit with the outcome of a request let through by
+// allow. Requests whose outcome says nothing about the health of the store,
+// for example because they were cancelled, release a probe without changing
+// the state of the circuit.
+func (b *CircuitBreaker) record(ctx context.Context, err error) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case ctx.Err() != nil, IsErrorTLS(err):
+		if b.state == BreakerHalfOpen {
+			b.state = BreakerOpen
+		}
+	case isStoreFailure(err):
+		b.failures++
+		if b.state == BreakerHalfOpen || b.failures >= b.options.FailureThreshold {
+			b.state = BreakerOpen
+			b.openedAt = b.now()
+		}
+	default:
+		b.state = BreakerClosed
+		b.failures = 0
+	}
+}
+
+func (b *CircuitBreaker) retryAt() time.Time {
+	return b.openedAt.Add(b.options.OpenTimeout)
+}
+
+// isStoreFailure reports whether the supplied error shows that the store is
+// unhealthy: it either did not answer, or answered with a server error. Any
+// other answer shows that it is up.
+func isStoreFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	if _, ok := StatusCode(err); ok {
+		return IsErrorServer(err)
+	}
+	return true
+}
+
+// A BreakerCache hands out one CircuitBreaker per pet store, keyed by server
+// URL, so that all the ProviderConfigs pointing to a store share its state.
+type BreakerCache struct {
+	options BreakerOptions
+
+	mu       sync.Mutex
+	breakers map[string]*CircuitBreaker
+}
+
+// NewBreakerCache returns an empty BreakerCache whose breakers use the
+// supplied options.
+func NewBreakerCache(o BreakerOptions) *BreakerCache {
+	return &BreakerCache{
+		options:  o,
+		breakers: map[string]*CircuitBreaker{},
+	}
+}
+
+// Get returns the CircuitBreaker of the supplied server URL.
+func (c *BreakerCache) Get(server string) *CircuitBreaker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, ok := c.breakers[server]
+	if !ok {
+		b = NewCircuitBreaker(server, c.options)
+		c.breakers[server] = b
+	}
+	return b
+}
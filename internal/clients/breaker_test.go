@@ -0,0 +1,104 @@
+package petstore
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	type step struct {
+		// status the store answers with, if the request reaches it.
+		status int
+		// elapsed time since the previous step.
+		elapsed time.Duration
+
+		wantSent        bool
+		wantUnavailable bool
+		wantState       BreakerState
+	}
+
+	cases := map[string]struct {
+		reason string
+		steps  []step
+	}{
+		"OpenAfterConsecutiveFailures": {
+			reason: "The circuit should open after two consecutive failures and fail fast while open.",
+			steps: []step{
+				{status: http.StatusInternalServerError, wantSent: true, wantState: BreakerClosed},
+				{status: http.StatusInternalServerError, wantSent: true, wantState: BreakerOpen},
+				{status: http.StatusOK, wantUnavailable: true, wantState: BreakerOpen},
+			},
+		},
+		"SuccessResetsFailures": {
+			reason: "Failures that are not consecutive should not open the circuit.",
+			steps: []step{
+				{status: http.StatusInternalServerError, wantSent: true, wantState: BreakerClosed},
+				{status: http.StatusOK, wantSent: true, wantState: BreakerClosed},
+				{status: http.StatusInternalServerError, wantSent: true, wantState: BreakerClosed},
+			},
+		},
+		"ClientErrorsAreNotFailures": {
+			reason: "A store that answers with a client error is up.",
+			steps: []step{
+				{status: http.StatusNotFound, wantSent: true, wantState: BreakerClosed},
+				{status: http.StatusNotFound, wantSent: true, wantState: BreakerClosed},
+			},
+		},
+		"ProbeSucceeds": {
+			reason: "A successful probe after the open timeout should close the circuit.",
+			steps: []step{
+				{status: http.StatusInternalServerError, wantSent: true, wantState: BreakerClosed},
+				{status: http.StatusInternalServerError, wantSent: true, wantState: BreakerOpen},
+				{status: http.StatusOK, elapsed: time.Minute, wantSent: true, wantState: BreakerClosed},
+			},
+		},
+		"ProbeFails": {
+			reason: "A failed probe should open the circuit again for another timeout.",
+			steps: []step{
+				{status: http.StatusInternalServerError, wantSent: true, wantState: BreakerClosed},
+				{status: http.StatusInternalServerError, wantSent: true, wantState: BreakerOpen},
+				{status: http.StatusBadGateway, elapsed: time.Minute, wantSent: true, wantState: BreakerOpen},
+				{status: http.StatusOK, elapsed: time.Second, wantUnavailable: true, wantState: BreakerOpen},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var status, sent int
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				sent++
+				w.WriteHeader(status)
+			}))
+			defer srv.Close()
+
+			now := time.Now()
+			b := NewCircuitBreaker(srv.URL, BreakerOptions{FailureThreshold: 2, OpenTimeout: 30 * time.Second})
+			b.now = func() time.Time { return now }
+			c := New(GetConfig(srv.URL, WithCircuitBreaker(b), WithRetryPolicy(RetryPolicy{MaxRetries: -1})))
+
+			for i, s := range tc.steps {
+				now = now.Add(s.elapsed)
+				status, sent = s.status, 0
+				res, err := c.DoRequest(context.Background(), "/pet/1", http.MethodGet, nil)
+				if res != nil {
+					res.Body.Close()
+				}
+				if diff := cmp.Diff(s.wantSent, sent == 1); diff != "" {
+					t.Errorf("\n%s\nstep %d: request sent: -want, +got:\n%s\n", tc.reason, i, diff)
+				}
+				if diff := cmp.Diff(s.wantUnavailable, IsErrorStoreUnavailable(err)); diff != "" {
+					t.Errorf("\n%s\nstep %d: IsErrorStoreUnavailable(...): -want, +got:\n%s\nerror: %v", tc.reason, i, diff, err)
+				}
+				if diff := cmp.Diff(s.wantState, b.State()); diff != "" {
+					t.Errorf("\n%s\nstep %d: b.State(): -want, +got:\n%s\n", tc.reason, i, diff)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package petstore
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A ResponseCache remembers the responses to GET requests sent to a pet store.
+// Responses younger than its TTL are served without contacting the store.
+// Older responses carrying an ETag or a Last-Modified header are revalidated
+// with a conditional request, so that an unchanged resource is not sent again.
+// Requests with any other method invalidate the cached response of their path.
+// Expired responses that cannot be revalidated are dropped whenever a response
+// is stored, and the cache holds at most maxCachedResponses responses,
+// dropping the one validated longest ago to make room for a new one. A nil
+// ResponseCache caches nothing.
+type ResponseCache struct {
+	ttl        time.Duration
+	now        func() time.Time
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]cachedResponse
+}
+
+type cachedResponse struct {
+	body         []byte
+	header       http.Header
+	etag         string
+	lastModified string
+	validatedAt  time.Time
+}
+
+// maxCachedResponses bounds the number of responses a ResponseCache holds.
+const maxCachedResponses = 1024
+
+// NewResponseCache returns an empty ResponseCache serving responses for the
+// supplied TTL. With a zero TTL every response is revalidated.
+func NewResponseCache(ttl time.Duration) *ResponseCache {
+	return &ResponseCache{
+		ttl:        ttl,
+		now:        time.Now,
+		maxEntries: maxCachedResponses,
+		entries:    map[string]cachedResponse{},
+	}
+}
+
+// Invalidate forgets the response cached for the supplied URL, if any.
+func (c *ResponseCache) Invalidate(url string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, url)
+}
+
+func (c *ResponseCache) lookup(url string) (cachedResponse, bool) {
+	if c == nil {
+		return cachedResponse{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[url]
+	if ok && c.expired(e) {
+		delete(c.entries, url)
+		return cachedResponse{}, false
+	}
+	return e, ok
+}
+
+// expired reports whether the supplied entry can no longer be used: it is not
+// fresh, and has no validators to revalidate it with.
+func (c *ResponseCache) expired(e cachedResponse) bool {
+	return !c.fresh(e) && e.etag == "" && e.lastModified == ""
+}
+
+// fresh reports whether the supplied entry may be served without contacting
+// the pet store.
+func (c *ResponseCache) fresh(e cachedResponse) bool {
+	return c.ttl > 0 && c.now().Sub(e.validatedAt) < c.ttl
+}
+
+// revalidate records that the pet store confirmed the response cached for
+// the supplied URL is still current.
+func (c *ResponseCache) revalidate(url string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[url]; ok {
+		e.validatedAt = c.now()
+		c.entries[url] = e
+	}
+}
+
+// store caches the supplied successful response if it may be reused, and
+// returns a response whose body can still be read by the caller.
+func (c *ResponseCache) store(url string, res *http.Response) (*http.Response, error) {
+	if c == nil || strings.Contains(res.Header.Get("Cache-Control"), "no-store") {
+		return res, nil
+	}
+	e := cachedResponse{
+		header:       res.Header.Clone(),
+		etag:         res.Header.Get("ETag"),
+		lastModified: res.Header.Get("Last-Modified"),
+	}
+	if c.ttl <= 0 && e.etag == "" && e.lastModified == "" {
+		return res, nil
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read response body: %w", err)
+	}
+	e.body = body
+
+	c.mu.Lock()
+	c.evictExpired()
+	if _, ok := c.entries[url]; !ok && len(c.entries) >= c.maxEntries {
+		c.evictOldest()
+	}
+	e.validatedAt = c.now()
+	c.entries[url] = e
+	c.mu.Unlock()
+	return e.response(res.Request), nil
+}
+
+// evictExpired drops the responses that can no longer be used. The caller must
+// hold the lock.
+func (c *ResponseCache) evictExpired() {
+	for url, e := range c.entries {
+		if c.expired(e) {
+			delete(c.entries, url)
+		}
+	}
+}
+
+// evictOldest drops the response validated longest ago. The caller must hold
+// the lock.
+func (c *ResponseCache) evictOldest() {
+	var oldest string
+	var oldestAt time.Time
+	for url, e := range c.entries {
+		if oldest == "" || e.validatedAt.Before(oldestAt) {
+			oldest, oldestAt = url, e.validatedAt
+		}
+	}
+	delete(c.entries, oldest)
+}
+
+// conditionalHeader returns the headers that ask the pet store to answer 304
+// Not Modified if the cached response is still current.
+func (e cachedResponse) conditionalHeader() http.Header {
+	h := http.Header{}
+	if e.etag != "" {
+		h.Set("If-None-Match", e.etag)
+	}
+	if e.lastModified != "" {
+		h.Set("If-Modified-Since", e.lastModified)
+	}
+	return h
+}
+
+// response returns the cached response as the answer to the supplied request.
+func (e cachedResponse) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+		Request:       req,
+	}
+}
+
+type cachedResponseCache struct {
+	ttl   time.Duration
+	cache *ResponseCache
+}
+
+// ResponseCaches hand out one ResponseCache per ProviderConfig, so that the
+// responses it caches serve all the resources using it. A ResponseCache is
+// replaced when the TTL of its ProviderConfig changes.
+type ResponseCaches struct {
+	mu     sync.Mutex
+	caches map[string]cachedResponseCache
+}
+
+// NewResponseCaches returns an empty set of ResponseCaches.
+func NewResponseCaches() *ResponseCaches {
+	return &ResponseCaches{
+		caches: map[string]cachedResponseCache{},
+	}
+}
+
+// Get returns the ResponseCache of the named ProviderConfig, building a new
+// one if none exists yet or if its TTL changed.
+func (c *ResponseCaches) Get(name string, ttl time.Duration) *ResponseCache {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached, ok := c.caches[name]
+	if ok && cached.ttl == ttl {
+		return cached.cache
+	}
+	rc := NewResponseCache(ttl)
+	c.caches[name] = cachedResponseCache{ttl: ttl, cache: rc}
+	return rc
+}
@@ -0,0 +1,257 @@
+package petstore
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestResponseCache(t *testing.T) {
+	type step struct {
+		method string
+		// header sent with the request.
+		header http.Header
+		// elapsed time since the previous step.
+		elapsed time.Duration
+
+		wantSent bool
+		// wantConditional is the conditional header the store receives.
+		wantConditional string
+		wantBody        string
+	}
+
+	cases := map[string]struct {
+		reason string
+		ttl    time.Duration
+		// header set by the store on its responses.
+		header map[string]string
+		steps  []step
+	}{
+		"RevalidateETag": {
+			reason: "A response with an ETag should be revalidated, and served from the cache when the store answers 304.",
+			header: map[string]string{"ETag": `"v1"`},
+			steps: []step{
+				{method: http.MethodGet, wantSent: true, wantBody: "1"},
+				{method: http.MethodGet, wantSent: true, wantConditional: `"v1"`, wantBody: "1"},
+				{method: http.MethodGet, wantSent: true, wantConditional: `"v1"`, wantBody: "1"},
+			},
+		},
+		"RevalidateLastModified": {
+			reason: "A response with a Last-Modified header should be revalidated.",
+			header: map[string]string{"Last-Modified": "Mon, 02 Jan 2006 15:04:05 GMT"},
+			steps: []step{
+				{method: http.MethodGet, wantSent: true, wantBody: "1"},
+				{method: http.MethodGet, wantSent: true, wantConditional: "Mon, 02 Jan 2006 15:04:05 GMT", wantBody: "1"},
+			},
+		},
+		"NoValidators": {
+			reason: "Without a TTL, a response without validators should not be cached.",
+			steps: []step{
+				{method: http.MethodGet, wantSent: true, wantBody: "1"},
+				{method: http.MethodGet, wantSent: true, wantBody: "2"},
+			},
+		},
+		"FreshWithinTTL": {
+			reason: "A response younger than the TTL should be served without contacting the store.",
+			ttl:    time.Minute,
+			steps: []step{
+				{method: http.MethodGet, wantSent: true, wantBody: "1"},
+				{method: http.MethodGet, elapsed: 30 * time.Second, wantBody: "1"},
+				{method: http.MethodGet, elapsed: time.Minute, wantSent: true, wantBody: "2"},
+			},
+		},
+		"NotStoredOnRequest": {
+			reason: "A response the caller asks not to store should neither be cached nor served from the cache.",
+			ttl:    time.Minute,
+			steps: []step{
+				{method: http.MethodGet, header: http.Header{"Cache-Control": []string{"no-store"}}, wantSent: true, wantBody: "1"},
+				{method: http.MethodGet, wantSent: true, wantBody: "2"},
+				{method: http.MethodGet, header: http.Header{"Cache-Control": []string{"no-store"}}, wantSent: true, wantBody: "3"},
+			},
+		},
+		"NoCacheOnRequest": {
+			reason: "A fresh response should be revalidated when the caller asks for no-cache.",
+			ttl:    time.Minute,
+			header: map[string]string{"ETag": `"v1"`},
+			steps: []step{
+				{method: http.MethodGet, wantSent: true, wantBody: "1"},
+				{method: http.MethodGet, wantBody: "1"},
+				{method: http.MethodGet, header: http.Header{"Cache-Control": []string{"no-cache"}}, wantSent: true, wantConditional: `"v1"`, wantBody: "1"},
+			},
+		},
+		"InvalidatedByWrite": {
+			reason: "A write to a path should invalidate its cached response, whatever the TTL.",
+			ttl:    time.Minute,
+			header: map[string]string{"ETag": `"v1"`},
+			steps: []step{
+				{method: http.MethodGet, wantSent: true, wantBody: "1"},
+				{method: http.MethodPut, wantSent: true},
+				{method: http.MethodGet, wantSent: true, wantBody: "3"},
+				{method: http.MethodDelete, wantSent: true},
+				{method: http.MethodGet, wantSent: true, wantBody: "5"},
+			},
+		},
+		"NoStore": {
+			reason: "A response the store forbids to cache should not be cached.",
+			ttl:    time.Minute,
+			header: map[string]string{"Cache-Control": "no-store"},
+			steps: []step{
+				{method: http.MethodGet, wantSent: true, wantBody: "1"},
+				{method: http.MethodGet, wantSent: true, wantBody: "2"},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var sent int
+			var conditional string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				sent++
+				conditional = r.Header.Get("If-None-Match") + r.Header.Get("If-Modified-Since")
+				for k, v := range tc.header {
+					w.Header().Set(k, v)
+				}
+				if conditional != "" {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				_, _ = io.WriteString(w, string(rune('0'+sent)))
+			}))
+			defer srv.Close()
+
+			now := time.Now()
+			rc := NewResponseCache(tc.ttl)
+			rc.now = func() time.Time { return now }
+			c := New(GetConfig(srv.URL, WithResponseCache(rc)))
+
+			for i, s := range tc.steps {
+				now = now.Add(s.elapsed)
+				before := sent
+				conditional = ""
+				res, err := c.DoRequestWithHeader(context.Background(), "/pet/1", s.method, nil, s.header)
+				if err != nil {
+					t.Fatalf("\n%s\nstep %d: c.DoRequest(...): %v", tc.reason, i, err)
+				}
+				body, _ := io.ReadAll(res.Body)
+				res.Body.Close()
+
+				if diff := cmp.Diff(s.wantSent, sent > before); diff != "" {
+					t.Errorf("\n%s\nstep %d: request sent: -want, +got:\n%s\n", tc.reason, i, diff)
+				}
+				if diff := cmp.Diff(s.wantConditional, conditional); diff != "" {
+					t.Errorf("\n%s\nstep %d: conditional header: -want, +got:\n%s\n", tc.reason, i, diff)
+				}
+				if diff := cmp.Diff(http.StatusOK, res.StatusCode); diff != "" {
+					t.Errorf("\n%s\nstep %d: status code: -want, +got:\n%s\n", tc.reason, i, diff)
+				}
+				if s.method == http.MethodGet {
+					if diff := cmp.Diff(s.wantBody, string(body)); diff != "" {
+						t.Errorf("\n%s\nstep %d: body: -want, +got:\n%s\n", tc.reason, i, diff)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestResponseCacheEviction(t *testing.T) {
+	type step struct {
+		path string
+		// elapsed time since the previous step.
+		elapsed time.Duration
+	}
+
+	cases := map[string]struct {
+		reason     string
+		ttl        time.Duration
+		maxEntries int
+		// header set by the store on its responses.
+		header map[string]string
+		steps  []step
+		// wantCached are the paths whose responses remain cached.
+		wantCached []string
+	}{
+		"Expired": {
+			reason:     "Expired responses without validators should be dropped.",
+			ttl:        time.Minute,
+			maxEntries: 10,
+			steps: []step{
+				{path: "/pet/1"},
+				{path: "/pet/2", elapsed: 30 * time.Second},
+				{path: "/pet/1", elapsed: time.Minute},
+			},
+			wantCached: []string{"/pet/1"},
+		},
+		"ExpiredWithValidators": {
+			reason:     "An expired response with validators should be kept for revalidation.",
+			ttl:        time.Minute,
+			maxEntries: 10,
+			header:     map[string]string{"ETag": `"v1"`},
+			steps: []step{
+				{path: "/pet/1"},
+				{path: "/pet/2", elapsed: 2 * time.Minute},
+			},
+			wantCached: []string{"/pet/1", "/pet/2"},
+		},
+		"Bounded": {
+			reason:     "A full cache should drop the response validated longest ago to make room.",
+			maxEntries: 2,
+			header:     map[string]string{"ETag": `"v1"`},
+			steps: []step{
+				{path: "/pet/1"},
+				{path: "/pet/2", elapsed: time.Second},
+				{path: "/pet/1", elapsed: time.Second},
+				{path: "/pet/3", elapsed: time.Second},
+			},
+			wantCached: []string{"/pet/1", "/pet/3"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for k, v := range tc.header {
+					w.Header().Set(k, v)
+				}
+				if r.Header.Get("If-None-Match") != "" {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				_, _ = io.WriteString(w, r.URL.Path)
+			}))
+			defer srv.Close()
+
+			now := time.Now()
+			rc := NewResponseCache(tc.ttl)
+			rc.now = func() time.Time { return now }
+			rc.maxEntries = tc.maxEntries
+			c := New(GetConfig(srv.URL, WithResponseCache(rc)))
+
+			for i, s := range tc.steps {
+				now = now.Add(s.elapsed)
+				res, err := c.DoRequest(context.Background(), s.path, http.MethodGet, nil)
+				if err != nil {
+					t.Fatalf("\n%s\nstep %d: c.DoRequest(...): %v", tc.reason, i, err)
+				}
+				res.Body.Close()
+			}
+
+			got := []string{}
+			for url := range rc.entries {
+				got = append(got, strings.TrimPrefix(url, srv.URL))
+			}
+			sort.Strings(got)
+			if diff := cmp.Diff(tc.wantCached, got); diff != "" {
+				t.Errorf("\n%s\ncached paths: -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
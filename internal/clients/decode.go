@@ -0,0 +1,280 @@
+package petstore
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// defaultMaxResponseSize bounds the bodies of responses when no other limit
+// is configured. Pets are a few hundred bytes.
+const defaultMaxResponseSize = 1 << 20
+
+// A DecodeError is returned when the body of a successful response of the pet
+// store cannot be decoded, for example because it is not JSON or is too large.
+type DecodeError struct {
+	Method      string
+	Path        string
+	ContentType string
+	Err         error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("DecodeError: cannot decode response to %s %s (Content-Type %q): %s", e.Method, e.Path, e.ContentType, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error { return e.Err }
+
+func IsErrorDecode(err error) bool {
+	var decodeError *DecodeError
+	return errors.As(err, &decodeError)
+}
+
+// An UnknownFieldsNotifier is called with the fields of a decoded response
+// that have no counterpart in the type it was decoded into. Fields are named
+// by their path, for example tags[0].color.
+type UnknownFieldsNotifier func(ctx context.Context, method, path string, fields []string)
+
+func (c *Config) maxResponseSize() int64 {
+	if c.maxResponse <= 0 {
+		return defaultMaxResponseSize
+	}
+	return c.maxResponse
+}
+
+// Decode decodes the body of a response of the pet store into v, and closes
+// it. A body that cannot be decoded is reported as a DecodeError. In strict
+// mode the fields of JSON bodies that v has no counterpart for are passed to
+// the UnknownFieldsNotifier.
+func (c *Client) Decode(res *http.Response, v any) error {
+	defer res.Body.Close()
+	ctx, method, path := requestOf(res)
+	derr := &DecodeError{Method: method, Path: path, ContentType: res.Header.Get("Content-Type")}
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		if IsErrorDecode(err) {
+			return err
+		}
+		derr.Err = fmt.Errorf("cannot read body: %w", err)
+		return derr
+	}
+
+	if isXML(c.config.format, res) {
+		if err := xml.Unmarshal(data, v); err != nil {
+			derr.Err = fmt.Errorf("invalid XML: %w", err)
+			return derr
+		}
+		return nil
+	}
+	if err := c.unmarshalJSON(ctx, method, path, data, v); err != nil {
+		derr.Err = err
+		return derr
+	}
+	return nil
+}
+
+// DecodeEach decodes the body of a response of the pet store holding a list,
+// passing its items to visit one at a time, and closes it. Only one item is
+// held in memory at any time, and the maximum response size bounds the size
+// of each item rather than that of the whole list. Decoding stops at the first
+// error returned by visit, which is returned as is.
+func DecodeEach[T any](c *Client, res *http.Response, visit func(*T) error) error {
+	defer res.Body.Close()
+	ctx, method, path := requestOf(res)
+	derr := func(err error) error {
+		if IsErrorDecode(err) {
+			return err
+		}
+		return &DecodeError{Method: method, Path: path, ContentType: res.Header.Get("Content-Type"), Err: err}
+	}
+	limit := c.config.maxResponseSize()
+	// Each item is bounded by the maximum response size once decoded. The
+	// body is bounded per item too, leaving as much room again for what the
+	// decoder reads ahead, so that a single item cannot be endless.
+	next := func(int64) {}
+	if body, ok := res.Body.(*limitedBody); ok {
+		body.limit = 2 * limit
+		next = func(offset int64) { body.base = offset }
+	}
+
+	if isXML(c.config.format, res) {
+		return decodeEachXML(xml.NewDecoder(res.Body), limit, derr, next, visit)
+	}
+
+	dec := json.NewDecoder(res.Body)
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		return derr(fmt.Errorf("invalid JSON: want an array, got %v (%v)", tok, err))
+	}
+	for dec.More() {
+		next(dec.InputOffset())
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return derr(fmt.Errorf("invalid JSON: %w", err))
+		}
+		if int64(len(raw)) > limit {
+			return derr(fmt.Errorf("item exceeds %d bytes", limit))
+		}
+		item := new(T)
+		if err := c.unmarshalJSON(ctx, method, path, raw, item); err != nil {
+			return derr(err)
+		}
+		if err := visit(item); err != nil {
+			return err
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return derr(fmt.Errorf("invalid JSON: %w", err))
+	}
+	return nil
+}
+
+// decodeEachXML decodes each child of the root element of an XML document as
+// an item of a list.
+func decodeEachXML[T any](dec *xml.Decoder, limit int64, derr func(error) error, next func(int64), visit func(*T) error) error {
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) && depth == 0 {
+			return nil
+		}
+		if err != nil {
+			return derr(fmt.Errorf("invalid XML: %w", err))
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if depth == 0 {
+				depth++
+				continue
+			}
+			start := dec.InputOffset()
+			next(start)
+			item := new(T)
+			if err := dec.DecodeElement(item, &t); err != nil {
+				return derr(fmt.Errorf("invalid XML: %w", err))
+			}
+			if dec.InputOffset()-start > limit {
+				return derr(fmt.Errorf("item exceeds %d bytes", limit))
+			}
+			if err := visit(item); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+}
+
+// requestOf returns the context, method and path of the request the supplied
+// response answers.
+func requestOf(res *http.Response) (context.Context, string, string) {
+	if req := res.Request; req != nil {
+		return req.Context(), req.Method, req.URL.Path
+	}
+	return context.Background(), "", ""
+}
+
+// unmarshalJSON decodes the supplied JSON document into v. In strict mode the
+// fields v has no counterpart for are passed to the UnknownFieldsNotifier.
+func (c *Client) unmarshalJSON(ctx context.Context, method, path string, data []byte, v any) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	if c.config.unknownFields != nil {
+		if fields := unknownFields(data, reflect.TypeOf(v), ""); len(fields) > 0 {
+			c.config.unknownFields(ctx, method, path, fields)
+		}
+	}
+	return nil
+}
+
+// unknownFields returns the paths of the fields of the supplied JSON document
+// that have no counterpart in t, sorted. Names are matched case-insensitively,
+// as encoding/json does.
+func unknownFields(data []byte, t reflect.Type, prefix string) []string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	var unknown []string
+	switch t.Kind() {
+	case reflect.Struct:
+		var obj map[string]json.RawMessage
+		if json.Unmarshal(data, &obj) != nil {
+			return nil
+		}
+		fields := jsonFields(t)
+		for name, value := range obj {
+			ft, ok := fields[strings.ToLower(name)]
+			if !ok {
+				unknown = append(unknown, prefix+name)
+				continue
+			}
+			unknown = append(unknown, unknownFields(value, ft, prefix+name+".")...)
+		}
+	case reflect.Slice, reflect.Array:
+		var items []json.RawMessage
+		if json.Unmarshal(data, &items) != nil {
+			return nil
+		}
+		for i, item := range items {
+			unknown = append(unknown, unknownFields(item, t.Elem(), fmt.Sprintf("%s[%d].", strings.TrimSuffix(prefix, "."), i))...)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// jsonFields returns the types of the fields of a struct type, keyed by their
+// lower case JSON name. Fields of embedded structs are promoted.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		switch {
+		case name == "-":
+			continue
+		case f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct:
+			for k, v := range jsonFields(f.Type) {
+				fields[k] = v
+			}
+			continue
+		case !f.IsExported():
+			continue
+		case name == "":
+			name = f.Name
+		}
+		fields[strings.ToLower(name)] = f.Type
+	}
+	return fields
+}
+
+// A limitedBody fails reads beyond the maximum size of a response with a
+// DecodeError, so that a misbehaving store cannot exhaust the memory of the
+// provider.
+type limitedBody struct {
+	io.ReadCloser
+	method string
+	path   string
+	limit  int64
+	read   int64
+	// base is the offset the limit applies from. DecodeEach moves it to the
+	// start of each item of a list.
+	base int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+	if b.read-b.base > b.limit {
+		return n, &DecodeError{Method: b.method, Path: b.path, Err: fmt.Errorf("body exceeds %d bytes", b.limit)}
+	}
+	return n, err
+}
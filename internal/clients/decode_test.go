@@ -0,0 +1,190 @@
+package petstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDecode(t *testing.T) {
+	type tag struct {
+		Name string `json:"name"`
+	}
+	type pet struct {
+		Name string `json:"name"`
+		Tags []tag  `json:"tags"`
+	}
+	type want struct {
+		pet       pet
+		decodeErr bool
+		unknown   []string
+	}
+
+	cases := map[string]struct {
+		reason string
+		body   string
+		strict bool
+		opts   []ConfigOption
+		want   want
+	}{
+		"Lenient": {
+			reason: "Unknown fields should be ignored when decoding is not strict.",
+			body:   `{"name":"doggie","color":"brown"}`,
+			want:   want{pet: pet{Name: "doggie"}},
+		},
+		"Strict": {
+			reason: "Unknown fields, including nested ones, should be reported when decoding is strict.",
+			body:   `{"Name":"doggie","color":"brown","tags":[{"name":"a"},{"name":"b","weight":2}]}`,
+			strict: true,
+			opts:   []ConfigOption{WithMaxResponseSize(1 << 10)},
+			want: want{
+				pet:     pet{Name: "doggie", Tags: []tag{{Name: "a"}, {Name: "b"}}},
+				unknown: []string{"color", "tags[1].weight"},
+			},
+		},
+		"NotJSON": {
+			reason: "A success body that is not JSON should be a DecodeError.",
+			body:   `<html>Maintenance</html>`,
+			want:   want{decodeErr: true},
+		},
+		"Empty": {
+			reason: "An empty success body should be a DecodeError.",
+			want:   want{decodeErr: true},
+		},
+		"TooLarge": {
+			reason: "A body larger than the maximum response size should be a DecodeError.",
+			body:   `{"name":"` + strings.Repeat("a", 64) + `"}`,
+			opts:   []ConfigOption{WithMaxResponseSize(32)},
+			want:   want{decodeErr: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.WriteString(w, tc.body)
+			}))
+			defer srv.Close()
+
+			got := want{}
+			opts := tc.opts
+			if tc.strict {
+				opts = append(opts, WithStrictDecoding(func(_ context.Context, _, _ string, fields []string) { got.unknown = fields }))
+			}
+			c := New(GetConfig(srv.URL, opts...))
+
+			res, err := c.DoRequest(context.Background(), "/pet/1", http.MethodGet, nil)
+			if err != nil {
+				t.Fatalf("\n%s\nc.DoRequest(...): %v", tc.reason, err)
+			}
+			err = c.Decode(res, &got.pet)
+			got.decodeErr = IsErrorDecode(err)
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nc.Decode(...): -want, +got:\n%s\nerror: %v", tc.reason, diff, err)
+			}
+		})
+	}
+}
+
+func TestDecodeEach(t *testing.T) {
+	type pet struct {
+		Name string `json:"name" xml:"name"`
+	}
+	type want struct {
+		names     []string
+		decodeErr bool
+		visitErr  bool
+	}
+
+	cases := map[string]struct {
+		reason      string
+		body        string
+		contentType string
+		stopAfter   int
+		opts        []ConfigOption
+		want        want
+	}{
+		"JSON": {
+			reason: "Each item of a JSON array should be visited in order.",
+			body:   `[{"name":"a"},{"name":"b"},{"name":"c"}]`,
+			want:   want{names: []string{"a", "b", "c"}},
+		},
+		"XML": {
+			reason:      "Each child of the root of an XML document should be visited in order.",
+			body:        `<?xml version="1.0"?><pets><Pet><name>a</name></Pet><Pet><name>b</name></Pet></pets>`,
+			contentType: "application/xml",
+			want:        want{names: []string{"a", "b"}},
+		},
+		"Empty": {
+			reason: "An empty array should visit nothing.",
+			body:   `[]`,
+		},
+		"LargeList": {
+			reason: "The maximum response size should bound each item, not the whole list.",
+			body:   `[` + strings.Repeat(`{"name":"a"},`, 16) + `{"name":"b"}]`,
+			opts:   []ConfigOption{WithMaxResponseSize(64)},
+			want:   want{names: append(strings.Split(strings.Repeat("a", 16), ""), "b")},
+		},
+		"LargeItem": {
+			reason: "An item larger than the maximum response size should be a DecodeError.",
+			body:   `[{"name":"a"},{"name":"` + strings.Repeat("b", 256) + `"}]`,
+			opts:   []ConfigOption{WithMaxResponseSize(64)},
+			want:   want{names: []string{"a"}, decodeErr: true},
+		},
+		"NotAnArray": {
+			reason: "A JSON body that is not an array should be a DecodeError.",
+			body:   `{"name":"a"}`,
+			want:   want{decodeErr: true},
+		},
+		"Truncated": {
+			reason: "A truncated JSON array should be a DecodeError.",
+			body:   `[{"name":"a"},{"na`,
+			want:   want{names: []string{"a"}, decodeErr: true},
+		},
+		"StoppedByVisit": {
+			reason:    "An error returned by visit should stop decoding and be returned.",
+			body:      `[{"name":"a"},{"name":"b"},{"name":"c"}]`,
+			stopAfter: 2,
+			want:      want{names: []string{"a", "b"}, visitErr: true},
+		},
+	}
+
+	errStop := errors.New("stop")
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tc.contentType != "" {
+					w.Header().Set("Content-Type", tc.contentType)
+				}
+				_, _ = io.WriteString(w, tc.body)
+			}))
+			defer srv.Close()
+
+			c := New(GetConfig(srv.URL, append(tc.opts, WithFormat(FormatAuto))...))
+			res, err := c.DoRequest(context.Background(), "/pet/findByStatus?status=available", http.MethodGet, nil)
+			if err != nil {
+				t.Fatalf("\n%s\nc.DoRequest(...): %v", tc.reason, err)
+			}
+
+			got := want{}
+			err = DecodeEach(c, res, func(p *pet) error {
+				got.names = append(got.names, p.Name)
+				if len(got.names) == tc.stopAfter {
+					return errStop
+				}
+				return nil
+			})
+			got.decodeErr = IsErrorDecode(err)
+			got.visitErr = errors.Is(err, errStop)
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nDecodeEach(...): -want, +got:\n%s\nerror: %v", tc.reason, diff, err)
+			}
+		})
+	}
+}
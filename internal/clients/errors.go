@@ -0,0 +1,146 @@
+package petstore
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// An APIError describes a response of the pet store that did not succeed.
+// It is returned as is for status codes that have no more specific error
+// type, and embedded in every such type.
+type APIError struct {
+	StatusCode int
+	Method     string
+	Path       string
+	Body       string
+}
+
+func (e *APIError) Error() string { return e.format(e.ErrorCode()) }
+
+// ErrorCode identifies the kind of error.
+func (e *APIError) ErrorCode() string { return "APIError" }
+
+// ErrorMessage returns the body of the failed response.
+func (e *APIError) ErrorMessage() string { return e.Body }
+
+// HTTPStatusCode returns the status code of the failed response.
+func (e *APIError) HTTPStatusCode() int { return e.StatusCode }
+
+func (e *APIError) format(code string) string {
+	if e.Method == "" {
+		return fmt.Sprintf("%s: %s", code, e.Body)
+	}
+	return fmt.Sprintf("%s: %s %s returned %d: %s", code, e.Method, e.Path, e.StatusCode, e.Body)
+}
+
+// ResourceNotFoundException is returned for a 404 response.
+type ResourceNotFoundException struct {
+	APIError
+}
+
+func (e *ResourceNotFoundException) Error() string     { return e.format(e.ErrorCode()) }
+func (e *ResourceNotFoundException) ErrorCode() string { return "ResourceNotFoundException" }
+
+// ValidationException is returned for 400 and 405 responses, when the store
+// rejects the request as invalid.
+type ValidationException struct {
+	APIError
+}
+
+func (e *ValidationException) Error() string     { return e.format(e.ErrorCode()) }
+func (e *ValidationException) ErrorCode() string { return "ValidationException" }
+
+// AccessDeniedException is returned for 401 and 403 responses, when the
+// store rejects the credentials of the request.
+type AccessDeniedException struct {
+	APIError
+}
+
+func (e *AccessDeniedException) Error() string     { return e.format(e.ErrorCode()) }
+func (e *AccessDeniedException) ErrorCode() string { return "AccessDeniedException" }
+
+// ConflictException is returned for a 409 response.
+type ConflictException struct {
+	APIError
+}
+
+func (e *ConflictException) Error() string     { return e.format(e.ErrorCode()) }
+func (e *ConflictException) ErrorCode() string { return "ConflictException" }
+
+// ThrottlingException is returned for a 429 response.
+type ThrottlingException struct {
+	APIError
+}
+
+func (e *ThrottlingException) Error() string     { return e.format(e.ErrorCode()) }
+func (e *ThrottlingException) ErrorCode() string { return "ThrottlingException" }
+
+// ServerException is returned for 5xx responses.
+type ServerException struct {
+	APIError
+}
+
+func (e *ServerException) Error() string     { return e.format(e.ErrorCode()) }
+func (e *ServerException) ErrorCode() string { return "ServerException" }
+
+func errorFromStatusCode(method string, path string, statusCode int, message string) (err error) {
+	base := APIError{StatusCode: statusCode, Method: method, Path: path, Body: message}
+	switch {
+	case statusCode == http.StatusNotFound:
+		err = &ResourceNotFoundException{APIError: base}
+	case statusCode == http.StatusBadRequest, statusCode == http.StatusMethodNotAllowed:
+		err = &ValidationException{APIError: base}
+	case statusCode == http.StatusUnauthorized, statusCode == http.StatusForbidden:
+		err = &AccessDeniedException{APIError: base}
+	case statusCode == http.StatusConflict:
+		err = &ConflictException{APIError: base}
+	case statusCode == http.StatusTooManyRequests:
+		err = &ThrottlingException{APIError: base}
+	case statusCode >= 500:
+		err = &ServerException{APIError: base}
+	default:
+		err = &base
+	}
+	return err
+}
+
+func IsErrorNotFound(err error) bool {
+	var notFoundError *ResourceNotFoundException
+	return errors.As(err, &notFoundError)
+}
+
+func IsErrorValidation(err error) bool {
+	var validationError *ValidationException
+	return errors.As(err, &validationError)
+}
+
+func IsErrorAccessDenied(err error) bool {
+	var accessDeniedError *AccessDeniedException
+	return errors.As(err, &accessDeniedError)
+}
+
+func IsErrorConflict(err error) bool {
+	var conflictError *ConflictException
+	return errors.As(err, &conflictError)
+}
+
+func IsErrorThrottling(err error) bool {
+	var throttlingError *ThrottlingException
+	return errors.As(err, &throttlingError)
+}
+
+func IsErrorServer(err error) bool {
+	var serverError *ServerException
+	return errors.As(err, &serverError)
+}
+
+// StatusCode returns the HTTP status code carried by the supplied error, if
+// it was caused by a response of the pet store that did not succeed.
+func StatusCode(err error) (int, bool) {
+	var apiError interface{ HTTPStatusCode() int }
+	if errors.As(err, &apiError) {
+		return apiError.HTTPStatusCode(), true
+	}
+	return 0, false
+}
@@ -0,0 +1,38 @@
+package petstore
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestErrorFromStatusCode(t *testing.T) {
+	cases := map[string]struct {
+		statusCode int
+		is         func(error) bool
+	}{
+		"NotFound":         {statusCode: http.StatusNotFound, is: IsErrorNotFound},
+		"BadRequest":       {statusCode: http.StatusBadRequest, is: IsErrorValidation},
+		"MethodNotAllowed": {statusCode: http.StatusMethodNotAllowed, is: IsErrorValidation},
+		"Unauthorized":     {statusCode: http.StatusUnauthorized, is: IsErrorAccessDenied},
+		"Forbidden":        {statusCode: http.StatusForbidden, is: IsErrorAccessDenied},
+		"Conflict":         {statusCode: http.StatusConflict, is: IsErrorConflict},
+		"TooManyRequests":  {statusCode: http.StatusTooManyRequests, is: IsErrorThrottling},
+		"BadGateway":       {statusCode: http.StatusBadGateway, is: IsErrorServer},
+		"InternalError":    {statusCode: http.StatusInternalServerError, is: IsErrorServer},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := errorFromStatusCode(http.MethodGet, "/pet/1", tc.statusCode, "boom")
+			if !tc.is(err) {
+				t.Errorf("errorFromStatusCode(...): unexpected error type %T", err)
+			}
+			code, ok := StatusCode(err)
+			if diff := cmp.Diff(tc.statusCode, code); !ok || diff != "" {
+				t.Errorf("StatusCode(...): -want, +got:\n%s\n", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package petstore
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"mime"
+	"net/http"
+)
+
+// A Format is the media type of the bodies exchanged with a pet store.
+type Format string
+
+// Formats understood by pet stores.
+const (
+	// FormatJSON exchanges application/json bodies. It is the default.
+	FormatJSON Format = "JSON"
+	// FormatXML exchanges application/xml bodies, for stores that only
+	// speak XML.
+	FormatXML Format = "XML"
+	// FormatAuto accepts both media types and decodes responses according
+	// to their Content-Type. Request bodies are sent as JSON.
+	FormatAuto Format = "Auto"
+)
+
+const (
+	mediaTypeJSON = "application/json"
+	mediaTypeXML  = "application/xml"
+)
+
+// MediaTypeMergePatch is the media type of JSON merge patches, as defined by
+// RFC 7396.
+const MediaTypeMergePatch = "application/merge-patch+json"
+
+func (f Format) accept() string {
+	switch f {
+	case FormatXML:
+		return mediaTypeXML
+	case FormatAuto:
+		return mediaTypeJSON + ", " + mediaTypeXML + ";q=0.9"
+	}
+	return mediaTypeJSON
+}
+
+func (f Format) contentType() string {
+	if f == FormatXML {
+		return mediaTypeXML
+	}
+	return mediaTypeJSON
+}
+
+// Marshal encodes v as the body of a request to the pet store.
+func (c *Client) Marshal(v any) ([]byte, error) {
+	if c.config.format.contentType() == mediaTypeXML {
+		return xml.Marshal(v)
+	}
+	return json.Marshal(v)
+}
+
+// isXML reports whether the body of the supplied response is XML. In auto
+// mode responses without a Content-Type are assumed to be JSON.
+func isXML(f Format, res *http.Response) bool {
+	switch f {
+	case FormatXML:
+		return true
+	case FormatAuto:
+		mt, _, err := mime.ParseMediaType(res.Header.Get("Content-Type"))
+		return err == nil && (mt == mediaTypeXML || mt == "text/xml")
+	}
+	return false
+}
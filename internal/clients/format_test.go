@@ -0,0 +1,136 @@
+package petstore
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFormat(t *testing.T) {
+	type body struct {
+		Name string `json:"name" xml:"name"`
+	}
+	type want struct {
+		accept      string
+		contentType string
+		sent        string
+		decoded     body
+	}
+
+	cases := map[string]struct {
+		reason  string
+		format  Format
+		headers map[string]string
+		// contentType and body of the response of the store.
+		contentType string
+		body        string
+		want        want
+	}{
+		"DefaultsToJSON": {
+			reason:      "Without a format, bodies should be exchanged as JSON.",
+			contentType: "application/json",
+			body:        `{"name":"doggie"}`,
+			want: want{
+				accept:      "application/json",
+				contentType: "application/json",
+				sent:        `{"name":"doggie"}`,
+				decoded:     body{Name: "doggie"},
+			},
+		},
+		"XML": {
+			reason:      "The XML format should exchange bodies as XML, whatever the Content-Type of the response.",
+			format:      FormatXML,
+			contentType: "text/plain",
+			body:        `<body><name>doggie</name></body>`,
+			want: want{
+				accept:      "application/xml",
+				contentType: "application/xml",
+				sent:        `<body><name>doggie</name></body>`,
+				decoded:     body{Name: "doggie"},
+			},
+		},
+		"StaticHeadersIgnored": {
+			reason:      "Static headers should not override the media types set by the client.",
+			format:      FormatXML,
+			headers:     map[string]string{"Accept": "application/json", "Content-Type": "application/json"},
+			contentType: "application/xml",
+			body:        `<body><name>doggie</name></body>`,
+			want: want{
+				accept:      "application/xml",
+				contentType: "application/xml",
+				sent:        `<body><name>doggie</name></body>`,
+				decoded:     body{Name: "doggie"},
+			},
+		},
+		"AutoXML": {
+			reason:      "The auto format should decode a response according to its Content-Type.",
+			format:      FormatAuto,
+			contentType: "application/xml; charset=utf-8",
+			body:        `<body><name>doggie</name></body>`,
+			want: want{
+				accept:      "application/json, application/xml;q=0.9",
+				contentType: "application/json",
+				sent:        `{"name":"doggie"}`,
+				decoded:     body{Name: "doggie"},
+			},
+		},
+		"AutoJSON": {
+			reason: "The auto format should decode a response without Content-Type as JSON.",
+			format: FormatAuto,
+			body:   `{"name":"doggie"}`,
+			want: want{
+				accept:      "application/json, application/xml;q=0.9",
+				contentType: "application/json",
+				sent:        `{"name":"doggie"}`,
+				decoded:     body{Name: "doggie"},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var accept, contentType, sent string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodGet {
+					accept = r.Header.Get("Accept")
+					w.Header()["Content-Type"] = []string{tc.contentType}
+					_, _ = io.WriteString(w, tc.body)
+					return
+				}
+				contentType = r.Header.Get("Content-Type")
+				b, _ := io.ReadAll(r.Body)
+				sent = string(b)
+			}))
+			defer srv.Close()
+			c := New(GetConfig(srv.URL, WithFormat(tc.format), WithHeaders(tc.headers)))
+
+			b, err := c.Marshal(body{Name: "doggie"})
+			if err != nil {
+				t.Fatalf("c.Marshal(...): %v", err)
+			}
+			res, err := c.DoRequest(context.Background(), "/pet", http.MethodPost, b)
+			if err != nil {
+				t.Fatalf("c.DoRequest(POST): %v", err)
+			}
+			res.Body.Close()
+
+			res, err = c.DoRequest(context.Background(), "/pet/1", http.MethodGet, nil)
+			if err != nil {
+				t.Fatalf("c.DoRequest(GET): %v", err)
+			}
+			var decoded body
+			if err := c.Decode(res, &decoded); err != nil {
+				t.Fatalf("c.Decode(...): %v", err)
+			}
+
+			got := want{accept: accept, contentType: contentType, sent: sent, decoded: decoded}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\n-want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
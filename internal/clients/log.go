@@ -0,0 +1,129 @@
+package petstore
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+const (
+	redacted = "REDACTED"
+
+	// maxDumpedBody bounds how much of a body is logged.
+	maxDumpedBody = 16 << 10
+
+	// maxErrorMessage bounds how much of the body of a failed response is
+	// quoted in its error, and so in the conditions of resources.
+	maxErrorMessage = 1 << 10
+)
+
+// sensitiveHeaders are never logged in the clear.
+var sensitiveHeaders = map[string]bool{
+	"Authorization":                       true,
+	"Proxy-Authorization":                 true,
+	"Cookie":                              true,
+	"Set-Cookie":                          true,
+	http.CanonicalHeaderKey(apiKeyHeader): true,
+}
+
+// observe logs the outcome of one attempt at a request at debug level, and
+// passes it on to the RequestObserver if one is configured.
+func (c *Client) observe(ctx context.Context, r Request, path string, res *http.Response) {
+	r.Route = Route(path)
+	kv := []any{"method", r.Method, "path", path, "attempt", r.Attempt, "latency", r.Latency}
+	if r.Throttled > 0 {
+		kv = append(kv, "throttled", r.Throttled)
+	}
+	if res != nil {
+		kv = append(kv, "status", res.StatusCode)
+		r.StatusCode = res.StatusCode
+	}
+	if r.Err != nil {
+		kv = append(kv, "error", c.redact(r.Err.Error(), ""))
+	}
+	c.config.logger.Debug("Pet store request", kv...)
+	if c.config.observer != nil {
+		c.config.observer(ctx, r)
+	}
+}
+
+// dumpRequest logs the headers and body of a request at debug level.
+func (c *Client) dumpRequest(req *http.Request, body []byte, token string) {
+	c.config.logger.Debug("Pet store request dump",
+		"method", req.Method,
+		"url", req.URL.String(),
+		"headers", c.redactHeaders(req.Header, token),
+		"body", c.redact(truncate(body, maxDumpedBody), token),
+	)
+}
+
+// dumpResponse logs the headers and body of a response at debug level. Only
+// as much of the body as is dumped, and no more than the maximum response
+// size, is read ahead; it is put back in front of the rest of the body so that
+// the caller can still consume it all.
+func (c *Client) dumpResponse(req *http.Request, res *http.Response, token string) {
+	limit := c.config.maxResponseSize()
+	if limit > maxDumpedBody {
+		limit = maxDumpedBody
+	}
+	head, err := io.ReadAll(io.LimitReader(res.Body, limit+1))
+	res.Body = &prefixedBody{Reader: io.MultiReader(bytes.NewReader(head), res.Body), Closer: res.Body}
+	kv := []any{
+		"method", req.Method,
+		"url", req.URL.String(),
+		"status", res.StatusCode,
+		"headers", c.redactHeaders(res.Header, token),
+		"body", c.redact(truncate(head, limit), token),
+	}
+	if err != nil {
+		kv = append(kv, "error", err.Error())
+	}
+	c.config.logger.Debug("Pet store response dump", kv...)
+}
+
+// redactHeaders returns the supplied headers as sorted "Name: value" lines,
+// with credentials and secret values redacted.
+func (c *Client) redactHeaders(h http.Header, token string) []string {
+	out := make([]string, 0, len(h))
+	for k, vs := range h {
+		for _, v := range vs {
+			if sensitiveHeaders[k] {
+				v = redacted
+			}
+			out = append(out, k+": "+c.redact(v, token))
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// redact replaces every secret value known to the client, and the supplied
+// access token, in s.
+func (c *Client) redact(s string, token string) string {
+	secrets := append([]string{c.config.apiKey, token}, c.config.redactedValues...)
+	for _, v := range secrets {
+		if v != "" {
+			s = strings.ReplaceAll(s, v, redacted)
+		}
+	}
+	return s
+}
+
+// truncate returns at most limit bytes of the supplied body, marking it as
+// truncated if it is longer.
+func truncate(body []byte, limit int64) string {
+	if int64(len(body)) > limit {
+		return string(body[:limit]) + "...(truncated)"
+	}
+	return string(body)
+}
+
+// A prefixedBody is a response body part of which was read ahead, and put back
+// in front of the rest.
+type prefixedBody struct {
+	io.Reader
+	io.Closer
+}
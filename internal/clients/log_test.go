@@ -0,0 +1,151 @@
+package petstore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+)
+
+// recordingLogger records the messages logged at debug level.
+type recordingLogger struct {
+	messages *[]string
+}
+
+func (l recordingLogger) Info(msg string, keysAndValues ...any) {}
+func (l recordingLogger) Debug(msg string, keysAndValues ...any) {
+	*l.messages = append(*l.messages, fmt.Sprint(msg, keysAndValues))
+}
+func (l recordingLogger) WithValues(keysAndValues ...any) logging.Logger { return l }
+
+func TestLogging(t *testing.T) {
+	const body = `{"id":1,"name":"rex","note":"tenant-s3cr3t"}`
+
+	cases := map[string]struct {
+		reason string
+		dump   bool
+		want   []string
+	}{
+		"Requests": {
+			reason: "Every request should be logged without its bodies.",
+			want:   []string{"Pet store request"},
+		},
+		"Dump": {
+			reason: "Bodies should be dumped when enabled.",
+			dump:   true,
+			want:   []string{"Pet store request dump", "Pet store response dump", "Pet store request"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Set-Cookie", "session=abc")
+				_, _ = io.WriteString(w, body)
+			}))
+			defer srv.Close()
+
+			var messages []string
+			c := New(GetConfig(srv.URL,
+				WithAPIKey("k3y"),
+				WithHeaders(map[string]string{"X-Tenant-Token": "tenant-s3cr3t"}),
+				WithRedactedValues("tenant-s3cr3t"),
+				WithLogger(recordingLogger{messages: &messages}),
+				WithBodyDump(tc.dump),
+			))
+			res, err := c.DoRequest(context.Background(), "/pet/1", http.MethodGet, nil)
+			if err != nil {
+				t.Fatalf("c.DoRequest(...): %v", err)
+			}
+			got, _ := io.ReadAll(res.Body)
+			res.Body.Close()
+			if diff := cmp.Diff(body, string(got)); diff != "" {
+				t.Errorf("\n%s\nresponse body: -want, +got:\n%s\n", tc.reason, diff)
+			}
+
+			var logged []string
+			for _, m := range messages {
+				logged = append(logged, strings.SplitN(m, "[", 2)[0])
+				for _, secret := range []string{"k3y", "tenant-s3cr3t", "session=abc"} {
+					if strings.Contains(m, secret) {
+						t.Errorf("\n%s\nlogged %q in the clear: %s", tc.reason, secret, m)
+					}
+				}
+			}
+			if diff := cmp.Diff(tc.want, logged); diff != "" {
+				t.Errorf("\n%s\nlogged messages: -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestDumpResponseTruncated(t *testing.T) {
+	body := strings.Repeat("0123456789", 2*maxDumpedBody/10)
+
+	cases := map[string]struct {
+		reason      string
+		maxResponse int64
+		wantDumped  int
+		wantErr     bool
+	}{
+		"MaxDumpedBody": {
+			reason:      "A body longer than is dumped should be truncated in the dump, yet read whole by the caller.",
+			maxResponse: int64(len(body)),
+			wantDumped:  maxDumpedBody,
+		},
+		"MaxResponseSize": {
+			reason:      "No more of a body than the maximum response size should be read to dump it.",
+			maxResponse: 64,
+			wantDumped:  64,
+			wantErr:     true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.WriteString(w, body)
+			}))
+			defer srv.Close()
+
+			var messages []string
+			c := New(GetConfig(srv.URL,
+				WithLogger(recordingLogger{messages: &messages}),
+				WithBodyDump(true),
+				WithMaxResponseSize(tc.maxResponse),
+			))
+			res, err := c.DoRequest(context.Background(), "/pet/1", http.MethodGet, nil)
+			if err != nil {
+				t.Fatalf("c.DoRequest(...): %v", err)
+			}
+			got, err := io.ReadAll(res.Body)
+			res.Body.Close()
+			if tc.wantErr != (err != nil) {
+				t.Errorf("\n%s\nreading the body: want error %t, got %v", tc.reason, tc.wantErr, err)
+			}
+			if !tc.wantErr && string(got) != body {
+				t.Errorf("\n%s\nthe caller should read the whole body, got %d bytes", tc.reason, len(got))
+			}
+
+			var dump string
+			for _, m := range messages {
+				if strings.HasPrefix(m, "Pet store response dump") {
+					dump = m
+				}
+			}
+			if !strings.Contains(dump, body[:tc.wantDumped]+"...(truncated)") {
+				t.Errorf("\n%s\nthe dump should hold the first %d bytes of the body, marked as truncated: %.100s", tc.reason, tc.wantDumped, dump)
+			}
+			if strings.Contains(dump, body[:tc.wantDumped+1]) {
+				t.Errorf("\n%s\nthe dump should hold no more than %d bytes of the body", tc.reason, tc.wantDumped)
+			}
+		})
+	}
+}
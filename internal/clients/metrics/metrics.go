@@ -0,0 +1,65 @@
+// Package metrics records Prometheus metrics about the requests sent to pet
+// stores. The metrics are registered on the controller-runtime registry, and
+// served on the metrics address of the provider.
+package metrics
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	petstore "github.com/alexisries/provider-petstore/internal/clients"
+)
+
+const (
+	namespace = "petstore"
+	subsystem = "client"
+
+	// codeError is the code reported for requests that got no response.
+	codeError = "error"
+)
+
+var (
+	requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "requests_total",
+		Help:      "Number of requests sent to pet stores, partitioned by status code. Every retry counts as a request.",
+	}, []string{"method", "path", "provider_config", "code"})
+
+	latency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "request_duration_seconds",
+		Help:      "Latency of the requests sent to pet stores.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "path", "provider_config"})
+
+	throttled = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "rate_limit_wait_seconds",
+		Help:      "Time requests waited for the rate and concurrency limits of their ProviderConfig before being sent.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"provider_config"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(requests, latency, throttled)
+}
+
+// Observer returns a RequestObserver that records metrics about the requests
+// sent on behalf of the named ProviderConfig.
+func Observer(providerConfig string) petstore.RequestObserver {
+	return func(_ context.Context, r petstore.Request) {
+		code := codeError
+		if r.StatusCode != 0 {
+			code = strconv.Itoa(r.StatusCode)
+		}
+		requests.WithLabelValues(r.Method, r.Route, providerConfig, code).Inc()
+		latency.WithLabelValues(r.Method, r.Route, providerConfig).Observe(r.Latency.Seconds())
+		throttled.WithLabelValues(providerConfig).Observe(r.Throttled.Seconds())
+	}
+}
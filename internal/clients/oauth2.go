@@ -0,0 +1,169 @@
+package petstore
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenExpiryDelta is how long before its expiry an access token is
+// considered stale and refreshed, so that it never expires in flight.
+const tokenExpiryDelta = 30 * time.Second
+
+// OAuth2Options configures the OAuth2 client credentials flow used to obtain
+// access tokens for the pet store.
+type OAuth2Options struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// A TokenSource obtains access tokens through the OAuth2 client credentials
+// flow and caches them until shortly before they expire.
+type TokenSource struct {
+	options    OAuth2Options
+	httpClient *http.Client
+	now        func() time.Time
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// NewTokenSource returns a TokenSource that requests tokens using the
+// supplied http.Client.
+func NewTokenSource(o OAuth2Options, hc *http.Client) *TokenSource {
+	return &TokenSource{
+		options:    o,
+		httpClient: hc,
+		now:        time.Now,
+	}
+}
+
+// Token returns a valid access token, requesting a new one if none is cached
+// or if the cached one is about to expire.
+func (s *TokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && (s.expiry.IsZero() || s.now().Add(tokenExpiryDelta).Before(s.expiry)) {
+		return s.token, nil
+	}
+
+	t, err := s.fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+	s.token = t.AccessToken
+	s.expiry = time.Time{}
+	if t.ExpiresIn > 0 {
+		s.expiry = s.now().Add(time.Duration(t.ExpiresIn) * time.Second)
+	}
+	return s.token, nil
+}
+
+// Invalidate drops the supplied token from the cache if it is still the
+// cached one, typically because the pet store rejected it.
+func (s *TokenSource) Invalidate(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token == token {
+		s.token = ""
+	}
+}
+
+func (s *TokenSource) setHTTPClient(hc *http.Client) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.httpClient = hc
+}
+
+func (s *TokenSource) fetch(ctx context.Context) (*tokenResponse, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(s.options.Scopes) > 0 {
+		form.Set("scope", strings.Join(s.options.Scopes, " "))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.options.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(s.options.ClientID), url.QueryEscape(s.options.ClientSecret))
+
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := handleResponse(http.MethodPost, s.options.TokenURL, res, nil, defaultMaxResponseSize); err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	t := &tokenResponse{}
+	if err := json.NewDecoder(io.LimitReader(res.Body, defaultMaxResponseSize)).Decode(t); err != nil {
+		return nil, err
+	}
+	if t.AccessToken == "" {
+		return nil, &AccessDeniedException{APIError: APIError{
+			StatusCode: res.StatusCode,
+			Method:     http.MethodPost,
+			Path:       s.options.TokenURL,
+			Body:       "token endpoint returned no access token",
+		}}
+	}
+	return t, nil
+}
+
+type cachedTokenSource struct {
+	options OAuth2Options
+	source  *TokenSource
+}
+
+// A TokenSourceCache hands out one TokenSource per ProviderConfig so that
+// access tokens are shared across reconciles. A TokenSource is replaced when
+// the OAuth2 options of its ProviderConfig change, for example when the
+// client secret is rotated.
+type TokenSourceCache struct {
+	mu      sync.Mutex
+	sources map[string]cachedTokenSource
+}
+
+// NewTokenSourceCache returns an empty TokenSourceCache.
+func NewTokenSourceCache() *TokenSourceCache {
+	return &TokenSourceCache{
+		sources: map[string]cachedTokenSource{},
+	}
+}
+
+// Get returns the TokenSource cached for the named ProviderConfig, building a
+// new one if none is cached yet or if its options changed.
+func (c *TokenSourceCache) Get(name string, o OAuth2Options, hc *http.Client) *TokenSource {
+	c.mu.Lock()
+	cached, ok := c.sources[name]
+	if !ok || !reflect.DeepEqual(cached.options, o) {
+		ts := NewTokenSource(o, hc)
+		c.sources[name] = cachedTokenSource{options: o, source: ts}
+		c.mu.Unlock()
+		return ts
+	}
+	c.mu.Unlock()
+
+	// The transport of a ProviderConfig may be rebuilt without its OAuth2
+	// options changing; tokens are then requested through the new one.
+	cached.source.setHTTPClient(hc)
+	return cached.source
+}
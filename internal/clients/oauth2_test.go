@@ -0,0 +1,172 @@
+package petstore
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// tokenServer is a local stand-in for an OAuth2 token endpoint. It issues the
+// tokens t1, t2, ... in order.
+type tokenServer struct {
+	*httptest.Server
+	issued    int
+	expiresIn int
+	scopes    []string
+}
+
+func newTokenServer(t *testing.T, expiresIn int) *tokenServer {
+	ts := &tokenServer{expiresIn: expiresIn}
+	ts.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "client" || secret != "s3cr3t" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "client_credentials" {
+			t.Errorf("token request: unexpected form %v", r.PostForm)
+		}
+		ts.scopes = append(ts.scopes, r.PostForm.Get("scope"))
+		ts.issued++
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"t%d","token_type":"bearer","expires_in":%d}`, ts.issued, ts.expiresIn)
+	}))
+	return ts
+}
+
+func TestOAuth2(t *testing.T) {
+	type want struct {
+		tokens  int
+		seen    []string
+		errCode int
+	}
+
+	cases := map[string]struct {
+		reason   string
+		requests int
+		accepted map[string]bool
+		want     want
+	}{
+		"TokenCached": {
+			reason:   "A token should be requested once and reused across requests.",
+			requests: 2,
+			accepted: map[string]bool{"Bearer t1": true},
+			want: want{
+				tokens: 1,
+				seen:   []string{"Bearer t1", "Bearer t1"},
+			},
+		},
+		"ReauthenticateOnUnauthorized": {
+			reason:   "A rejected token should trigger one re-authentication.",
+			requests: 1,
+			accepted: map[string]bool{"Bearer t2": true},
+			want: want{
+				tokens: 2,
+				seen:   []string{"Bearer t1", "Bearer t2"},
+			},
+		},
+		"ReauthenticateOnlyOnce": {
+			reason:   "A request should not re-authenticate more than once.",
+			requests: 1,
+			accepted: map[string]bool{},
+			want: want{
+				tokens:  2,
+				seen:    []string{"Bearer t1", "Bearer t2"},
+				errCode: http.StatusUnauthorized,
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			tokens := newTokenServer(t, 3600)
+			defer tokens.Close()
+
+			var seen []string
+			store := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				auth := r.Header.Get("Authorization")
+				seen = append(seen, auth)
+				if !tc.accepted[auth] {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer store.Close()
+
+			hc, _ := NewHTTPClient(TransportOptions{})
+			ts := NewTokenSourceCache().Get("example", OAuth2Options{
+				TokenURL:     tokens.URL,
+				ClientID:     "client",
+				ClientSecret: "s3cr3t",
+				Scopes:       []string{"write:pets", "read:pets"},
+			}, hc)
+			c := New(GetConfig(store.URL, WithHTTPClient(hc), WithTokenSource(ts)))
+
+			var err error
+			for i := 0; i < tc.requests; i++ {
+				var res *http.Response
+				res, err = c.DoRequest(context.Background(), "/pet/1", http.MethodGet, nil)
+				if res != nil {
+					res.Body.Close()
+				}
+			}
+
+			code, _ := StatusCode(err)
+			if diff := cmp.Diff(tc.want.errCode, code); diff != "" {
+				t.Errorf("\n%s\nc.DoRequest(...): -want status, +got status:\n%s\nerror: %v", tc.reason, diff, err)
+			}
+			if diff := cmp.Diff(tc.want.tokens, tokens.issued); diff != "" {
+				t.Errorf("\n%s\ntokens issued: -want, +got:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.seen, seen); diff != "" {
+				t.Errorf("\n%s\nAuthorization headers: -want, +got:\n%s\n", tc.reason, diff)
+			}
+			for _, s := range tokens.scopes {
+				if s != "write:pets read:pets" {
+					t.Errorf("token request: unexpected scope %q", s)
+				}
+			}
+		})
+	}
+}
+
+func TestTokenSourceRefreshBeforeExpiry(t *testing.T) {
+	tokens := newTokenServer(t, 60)
+	defer tokens.Close()
+
+	now := time.Now()
+	ts := NewTokenSource(OAuth2Options{TokenURL: tokens.URL, ClientID: "client", ClientSecret: "s3cr3t"}, http.DefaultClient)
+	ts.now = func() time.Time { return now }
+
+	var got []string
+	for _, elapsed := range []time.Duration{0, 20 * time.Second, 40 * time.Second} {
+		now = now.Add(elapsed)
+		tok, err := ts.Token(context.Background())
+		if err != nil {
+			t.Fatalf("ts.Token(...): %v", err)
+		}
+		got = append(got, tok)
+	}
+
+	// The token expires after 60s and is refreshed once less than 30s of
+	// its lifetime remain.
+	if diff := cmp.Diff([]string{"t1", "t1", "t2"}, got); diff != "" {
+		t.Errorf("ts.Token(...): -want, +got:\n%s\n", diff)
+	}
+}
+
+func TestTokenSourceInvalidClient(t *testing.T) {
+	tokens := newTokenServer(t, 60)
+	defer tokens.Close()
+
+	ts := NewTokenSource(OAuth2Options{TokenURL: tokens.URL, ClientID: "client", ClientSecret: "wrong"}, http.DefaultClient)
+	if _, err := ts.Token(context.Background()); !IsErrorAccessDenied(err) {
+		t.Errorf("ts.Token(...): want AccessDeniedException, got %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package petstore
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// unknownRoute is reported for paths that match none of the known routes, so
+// that arbitrary paths cannot blow up the cardinality of metrics.
+const unknownRoute = "unknown"
+
+// routes of the pet store API. Routes with literal segments come before the
+// routes with parameters they overlap with, as the first match wins.
+var routes = []string{
+	"/pet",
+	"/pet/findByStatus",
+	"/pet/findByTags",
+	"/pet/{petId}",
+	"/pet/{petId}/uploadImage",
+	"/store/inventory",
+	"/store/order",
+	"/store/order/{orderId}",
+	"/user",
+	"/user/createWithArray",
+	"/user/createWithList",
+	"/user/login",
+	"/user/logout",
+	"/user/{username}",
+}
+
+// A Request describes one attempt at a request to the pet store.
+type Request struct {
+	Method string
+	// Route is the templated path of the request, for example
+	// /pet/{petId}.
+	Route string
+	// Attempt is zero for the first attempt, and the number of the retry
+	// otherwise.
+	Attempt int
+	// StatusCode of the response, or zero if no response was received.
+	StatusCode int
+	// Err is the network error that caused the attempt to fail, if any.
+	Err error
+	// Latency is how long it took to receive the response, excluding the
+	// time spent waiting for the rate limit.
+	Latency time.Duration
+	// Throttled is how long the request waited for the rate limit of its
+	// ProviderConfig before being sent.
+	Throttled time.Duration
+}
+
+// A RequestObserver is called after every attempt at a request, for example
+// to record metrics about it.
+type RequestObserver func(ctx context.Context, r Request)
+
+// Route returns the template of the pet store route the supplied path
+// matches, for example /pet/{petId} for /pet/42.
+func Route(path string) string {
+	if i := strings.IndexAny(path, "?#"); i >= 0 {
+		path = path[:i]
+	}
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for _, r := range routes {
+		if matchRoute(strings.Split(strings.Trim(r, "/"), "/"), segments) {
+			return r
+		}
+	}
+	return unknownRoute
+}
+
+func matchRoute(route, segments []string) bool {
+	if len(route) != len(segments) {
+		return false
+	}
+	for i, s := range route {
+		param := strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}")
+		if (param && segments[i] == "") || (!param && s != segments[i]) {
+			return false
+		}
+	}
+	return true
+}
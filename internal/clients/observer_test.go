@@ -0,0 +1,63 @@
+package petstore
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestRoute(t *testing.T) {
+	cases := map[string]struct {
+		path string
+		want string
+	}{
+		"Collection":  {path: "/pet", want: "/pet"},
+		"Parameter":   {path: "/pet/42", want: "/pet/{petId}"},
+		"Literal":     {path: "/pet/findByStatus?status=sold", want: "/pet/findByStatus"},
+		"Nested":      {path: "/pet/42/uploadImage", want: "/pet/{petId}/uploadImage"},
+		"OtherParam":  {path: "/user/jane", want: "/user/{username}"},
+		"Unknown":     {path: "/pets/42", want: unknownRoute},
+		"EmptyParam":  {path: "/pet//uploadImage", want: unknownRoute},
+		"TooManySegs": {path: "/pet/42/uploadImage/1", want: unknownRoute},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, Route(tc.path)); diff != "" {
+				t.Errorf("Route(%q): -want, +got:\n%s\n", tc.path, diff)
+			}
+		})
+	}
+}
+
+func TestRequestObserver(t *testing.T) {
+	codes := []int{http.StatusServiceUnavailable, http.StatusOK}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(codes[0])
+		codes = codes[1:]
+	}))
+	defer srv.Close()
+
+	var got []Request
+	c := New(GetConfig(srv.URL,
+		WithRetryPolicy(RetryPolicy{InitialBackoff: 1, MaxBackoff: 1}),
+		WithRequestObserver(func(_ context.Context, r Request) { got = append(got, r) }),
+	))
+	res, err := c.DoRequest(context.Background(), "/pet/42", http.MethodGet, nil)
+	if err != nil {
+		t.Fatalf("c.DoRequest(...): %v", err)
+	}
+	res.Body.Close()
+
+	want := []Request{
+		{Method: http.MethodGet, Route: "/pet/{petId}", Attempt: 0, StatusCode: http.StatusServiceUnavailable},
+		{Method: http.MethodGet, Route: "/pet/{petId}", Attempt: 1, StatusCode: http.StatusOK},
+	}
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreFields(Request{}, "Latency")); diff != "" {
+		t.Errorf("observed requests: -want, +got:\n%s\n", diff)
+	}
+}
@@ -1,14 +1,37 @@
 package pet
 
 import (
+	"bytes"
+	"context"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math/big"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
 
 	petstore "github.com/alexisries/provider-petstore/internal/clients"
 )
 
+// An IDStrategy determines who picks the ID of a new pet.
+type IDStrategy string
+
+// ID strategies.
+const (
+	// IDStrategyServer lets the pet store assign the ID of a new pet, and
+	// reads it from the response to its creation. It is the default.
+	IDStrategyServer IDStrategy = "Server"
+
+	// IDStrategyClient picks a random ID for a new pet, after checking that
+	// no pet has it yet.
+	IDStrategyClient IDStrategy = "Client"
+)
+
 const (
 	PetStatusPending    PetStatus = "PENDING"
 	PetStatusAvailable  PetStatus = "AVAILABLE"
@@ -18,24 +41,33 @@ const (
 )
 
 type Category struct {
-	Id   *int64  `json:"id,omitempty"`
-	Name *string `json:"name,omitempty"`
+	Id   *int64  `json:"id,omitempty" xml:"id,omitempty"`
+	Name *string `json:"name,omitempty" xml:"name,omitempty"`
 }
 
 type PetStatus string
 
 type Tag struct {
-	Id   *int64  `json:"id,omitempty"`
-	Name *string `json:"name,omitempty"`
+	Id   *int64  `json:"id,omitempty" xml:"id,omitempty"`
+	Name *string `json:"name,omitempty" xml:"name,omitempty"`
 }
 
+// A Pet is encoded as JSON or XML. Its XML representation follows the
+// petstore specification, for example <Pet><photoUrls><photoUrl>...
 type Pet struct {
-	Category  *Category `json:"category,omitempty"`
-	Id        *int64    `json:"id,omitempty"`
-	Name      string    `json:"name"`
-	PhotoUrls []string  `json:"photoUrls"`
-	Status    PetStatus `json:"status,omitempty"`
-	Tags      *[]Tag    `json:"tags,omitempty"`
+	Category  *Category `json:"category,omitempty" xml:"category,omitempty"`
+	Id        *int64    `json:"id,omitempty" xml:"id,omitempty"`
+	Name      string    `json:"name" xml:"name"`
+	PhotoUrls []string  `json:"photoUrls" xml:"photoUrls>photoUrl"`
+	Status    PetStatus `json:"status,omitempty" xml:"status,omitempty"`
+	Tags      *[]Tag    `json:"tags,omitempty" xml:"tags>tag,omitempty"`
+}
+
+// An ApiResponse is the answer of the pet store to an image upload.
+type ApiResponse struct {
+	Code    int32  `json:"code,omitempty" xml:"code,omitempty"`
+	Type    string `json:"type,omitempty" xml:"type,omitempty"`
+	Message string `json:"message,omitempty" xml:"message,omitempty"`
 }
 
 type PetClient struct {
@@ -48,64 +80,214 @@ func New(cfg *petstore.Config) PetClient {
 	}
 }
 
-func genRandNum(min, max int64) int64 {
+// NewPetID returns a random ID for a pet about to be added with the client
+// ID strategy.
+func NewPetID() (int64, error) {
+	return genRandNum(100000, 999999)
+}
+
+func genRandNum(min, max int64) (int64, error) {
 	bg := big.NewInt(max - min)
 	n, err := rand.Int(rand.Reader, bg)
 	if err != nil {
-		panic(err)
+		return 0, fmt.Errorf("cannot generate random number: %w", err)
 	}
-	return n.Int64() + min
+	return n.Int64() + min, nil
 }
 
-func (c *PetClient) AddPet(pet *Pet) (*Pet, error) {
-	randomInt := genRandNum(100000, 999999)
+// AddPet adds the supplied pet to the store, and returns the pet as the store
+// created it. A pet without ID is assigned one by the store. A non-empty
+// idempotency key is sent in the Idempotency-Key header, so that stores
+// honouring it create the pet at most once however many times the request is
+// sent. Such a request is also retried like an idempotent one.
+func (c *PetClient) AddPet(ctx context.Context, pet *Pet, idempotencyKey string) (*Pet, error) {
 	path := "/pet"
 	pet.Status = PetStatusPending
-	pet.Id = &randomInt
-	body, err := json.Marshal(*pet)
+	body, err := c.Marshal(*pet)
 	if err != nil {
 		return nil, err
 	}
-	_, err = c.DoRequest(path, "POST", body)
+	var header http.Header
+	if idempotencyKey != "" {
+		header = http.Header{petstore.IdempotencyKeyHeader: []string{idempotencyKey}}
+	}
+	res, err := c.DoRequestWithHeader(ctx, path, "POST", body, header)
 	if err != nil {
 		return nil, err
 	}
-	return pet, nil
+	var created Pet
+	if err := c.Decode(res, &created); err != nil {
+		return nil, err
+	}
+	if created.Id == nil || *created.Id == 0 {
+		if pet.Id == nil {
+			return nil, errors.New("pet store did not assign an ID to the new pet")
+		}
+		created.Id = pet.Id
+	}
+	// The pet is read from its own path, which the POST did not invalidate.
+	c.InvalidateCache(petstore.Path("/pet/{petId}", strconv.FormatInt(*created.Id, 10)))
+	return &created, nil
+}
+
+func (c *PetClient) GetPetById(ctx context.Context, petId string) (*Pet, error) {
+	return c.getPetById(ctx, petId, nil)
+}
+
+// GetPetByIdUncached is like GetPetById, but has the store revalidate a
+// cached response even when it is fresh. Reads that a write is based on use
+// it, so that they never act on a stale pet.
+func (c *PetClient) GetPetByIdUncached(ctx context.Context, petId string) (*Pet, error) {
+	return c.getPetById(ctx, petId, http.Header{"Cache-Control": []string{"no-cache"}})
 }
 
-func (c *PetClient) GetPetById(petId string) (*Pet, error) {
-	path := fmt.Sprintf("/pet/%s", petId)
-	res, err := c.DoRequest(path, "GET", nil)
+func (c *PetClient) getPetById(ctx context.Context, petId string, header http.Header) (*Pet, error) {
+	path := petstore.Path("/pet/{petId}", petId)
+	res, err := c.DoRequestWithHeader(ctx, path, "GET", nil, header)
 	if err != nil {
 		return nil, err
 	}
 	var pet Pet
-	defer res.Body.Close()
-	err = json.NewDecoder(res.Body).Decode(&pet)
-	if err != nil {
-		return &Pet{}, err
+	if err := c.Decode(res, &pet); err != nil {
+		return nil, err
 	}
 	return &pet, nil
 }
 
-func (c *PetClient) UpdatePetById(petId string, pet *Pet) error {
-	path := fmt.Sprintf("/pet/%s", petId)
-	body, err := json.Marshal(*pet)
+// UpdatePetById replaces the pet with the supplied ID, and returns the pet as
+// the store updated it.
+func (c *PetClient) UpdatePetById(ctx context.Context, petId string, pet *Pet) (*Pet, error) {
+	path := petstore.Path("/pet/{petId}", petId)
+	body, err := c.Marshal(*pet)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	res, err := c.DoRequest(ctx, path, "PUT", body)
+	if err != nil {
+		return nil, err
+	}
+	var updated Pet
+	if err := c.Decode(res, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// PatchPet applies the supplied JSON merge patch to the pet with the supplied
+// ID, and returns the pet as the store updated it. Stores that do not support
+// it fail with an error for which IsErrorPatchUnsupported is true.
+func (c *PetClient) PatchPet(ctx context.Context, petId string, patch PetPatch) (*Pet, error) {
+	path := petstore.Path("/pet/{petId}", petId)
+	// Merge patches are JSON whatever the format of the store.
+	body, err := json.Marshal(patch)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.DoRequestWithHeader(ctx, path, "PATCH", body, http.Header{"Content-Type": []string{petstore.MediaTypeMergePatch}})
+	if err != nil {
+		return nil, err
 	}
-	_, err = c.DoRequest(path, "PUT", body)
+	var patched Pet
+	if err := c.Decode(res, &patched); err != nil {
+		return nil, err
+	}
+	return &patched, nil
+}
+
+// UpdatePetWithForm sets the name, the status or both of the pet with the
+// supplied ID, leaving its other fields as they are. Empty values are not
+// sent.
+func (c *PetClient) UpdatePetWithForm(ctx context.Context, petId string, name string, status PetStatus) error {
+	form := url.Values{}
+	if name != "" {
+		form.Set("name", name)
+	}
+	if status != "" {
+		form.Set("status", string(status))
+	}
+	path := petstore.Path("/pet/{petId}", petId)
+	res, err := c.DoRequestWithHeader(ctx, path, "POST", []byte(form.Encode()), http.Header{"Content-Type": []string{"application/x-www-form-urlencoded"}})
 	if err != nil {
 		return err
 	}
+	res.Body.Close()
 	return nil
 }
 
-func (c *PetClient) DeletePetById(petId string) error {
-	path := fmt.Sprintf("/pet/%s", petId)
-	_, err := c.DoRequest(path, "DELETE", nil)
+func (c *PetClient) DeletePetById(ctx context.Context, petId string) error {
+	path := petstore.Path("/pet/{petId}", petId)
+	res, err := c.DoRequest(ctx, path, "DELETE", nil)
 	if err != nil {
 		return err
 	}
+	res.Body.Close()
 	return nil
 }
+
+// FindPetsByStatus passes the pets having any of the supplied statuses to
+// visit, one at a time, as they are decoded from the response of the store.
+// The response is neither cached nor held in memory as a whole, however many
+// pets it lists.
+func (c *PetClient) FindPetsByStatus(ctx context.Context, status []PetStatus, visit func(*Pet) error) error {
+	q := url.Values{}
+	for _, s := range status {
+		q.Add("status", string(s))
+	}
+	return c.findPets(ctx, "/pet/findByStatus?"+q.Encode(), visit)
+}
+
+// FindPetsByTags passes the pets having any of the supplied tags to visit, one
+// at a time, like FindPetsByStatus.
+func (c *PetClient) FindPetsByTags(ctx context.Context, tags []string, visit func(*Pet) error) error {
+	q := url.Values{"tags": tags}
+	return c.findPets(ctx, "/pet/findByTags?"+q.Encode(), visit)
+}
+
+func (c *PetClient) findPets(ctx context.Context, path string, visit func(*Pet) error) error {
+	res, err := c.StreamRequest(ctx, path, nil)
+	if err != nil {
+		return err
+	}
+	return petstore.DecodeEach(c.Client, res, visit)
+}
+
+// UploadImage uploads an image of the pet with the supplied ID as a multipart
+// form, along with its additional metadata if any. The boundary of the form
+// is derived from the image, so that uploading the same image twice sends the
+// same request.
+func (c *PetClient) UploadImage(ctx context.Context, petId string, fileName string, image []byte, additionalMetadata string) (*ApiResponse, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	sum := sha256.Sum256(image)
+	if err := w.SetBoundary(hex.EncodeToString(sum[:])[:32]); err != nil {
+		return nil, err
+	}
+	if additionalMetadata != "" {
+		if err := w.WriteField("additionalMetadata", additionalMetadata); err != nil {
+			return nil, err
+		}
+	}
+	part, err := w.CreateFormFile("file", fileName)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(image); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	path := petstore.Path("/pet/{petId}/uploadImage", petId)
+	res, err := c.DoRequestWithHeader(ctx, path, "POST", body.Bytes(), http.Header{"Content-Type": []string{w.FormDataContentType()}})
+	if err != nil {
+		return nil, err
+	}
+	// The photo URLs of the pet may have changed.
+	c.InvalidateCache(petstore.Path("/pet/{petId}", petId))
+	var r ApiResponse
+	if err := c.Decode(res, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
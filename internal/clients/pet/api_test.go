@@ -0,0 +1,332 @@
+package pet
+
+import (
+	"context"
+	"encoding/xml"
+	"flag"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	petstore "github.com/alexisries/provider-petstore/internal/clients"
+	"github.com/alexisries/provider-petstore/internal/clients/replay"
+)
+
+// Fixtures are replayed by default. Run the tests with -record to record them
+// again against the pet store at $PETSTORE_URL, using the API key in
+// $PETSTORE_API_KEY. The Patched case of TestPatchPet must be recorded against
+// a store that accepts JSON merge patches, which petstore.swagger.io does not.
+var record = flag.Bool("record", false, "record fixtures against a live pet store")
+
+const (
+	defaultServer = "https://petstore.swagger.io/v2"
+	defaultAPIKey = "special-key"
+	testPetID     = 424242
+)
+
+// newTestClient returns a client whose requests are recorded to or replayed
+// from the fixture named after the test. It does not retry failed requests.
+func newTestClient(t *testing.T) *PetClient {
+	t.Helper()
+	server, apiKey := defaultServer, defaultAPIKey
+	if *record {
+		if v := os.Getenv("PETSTORE_URL"); v != "" {
+			server = v
+		}
+		if v := os.Getenv("PETSTORE_API_KEY"); v != "" {
+			apiKey = v
+		}
+	}
+
+	fixture := filepath.Join("testdata", t.Name()+".json")
+	var rt *replay.Transport
+	if *record {
+		rt = replay.NewRecorder(fixture, nil, apiKey)
+	} else {
+		var err error
+		if rt, err = replay.NewReplayer(fixture, apiKey); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Cleanup(func() {
+		if err := rt.Save(); err != nil {
+			t.Errorf("cannot save fixture: %v", err)
+		}
+		if err := rt.Done(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	c := New(petstore.GetConfig(server,
+		petstore.WithHTTPClient(&http.Client{Transport: rt}),
+		petstore.WithAPIKey(apiKey),
+		petstore.WithRetryPolicy(petstore.RetryPolicy{MaxRetries: -1}),
+	))
+	return &c
+}
+
+func testPet() *Pet {
+	return &Pet{
+		Category:  &Category{Id: petstore.Int64(1), Name: petstore.String("dogs")},
+		Name:      "doggie",
+		PhotoUrls: []string{"https://example.com/doggie.png"},
+		Tags:      &[]Tag{{Id: petstore.Int64(1), Name: petstore.String("friendly")}},
+	}
+}
+
+// existingPet returns the pet the fixtures hold.
+func existingPet() *Pet {
+	p := testPet()
+	p.Id = petstore.Int64(testPetID)
+	p.Status = PetStatusPending
+	return p
+}
+
+func TestAddPet(t *testing.T) {
+	withID := testPet()
+	withID.Id = petstore.Int64(testPetID)
+
+	// The fixtures check the request sent: without an ID for a pet the store
+	// assigns one to, with its ID otherwise.
+	cases := map[string]struct {
+		reason string
+		pet    *Pet
+		key    string
+	}{
+		"ServerAssignedID": {
+			reason: "A pet without ID should be sent without one, and given the ID assigned by the store.",
+			pet:    testPet(),
+			key:    "pet-uid",
+		},
+		"ClientAssignedID": {
+			reason: "A pet with an ID should be added with it.",
+			pet:    withID,
+			key:    "pet-uid-424242",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := newTestClient(t).AddPet(context.Background(), tc.pet, tc.key)
+			if err != nil {
+				t.Fatalf("\n%s\nc.AddPet(...): %v", tc.reason, err)
+			}
+			if diff := cmp.Diff(existingPet(), got); diff != "" {
+				t.Errorf("\n%s\nc.AddPet(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestGetPetById(t *testing.T) {
+	type want struct {
+		pet      *Pet
+		notFound bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		id     string
+		want   want
+	}{
+		"Found": {
+			reason: "An existing pet should be decoded from the response.",
+			id:     "424242",
+			want:   want{pet: existingPet()},
+		},
+		"NotFound": {
+			reason: "A missing pet should be reported as a ResourceNotFoundException.",
+			id:     "404404",
+			want:   want{notFound: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := newTestClient(t).GetPetById(context.Background(), tc.id)
+			if diff := cmp.Diff(tc.want.notFound, petstore.IsErrorNotFound(err)); diff != "" {
+				t.Errorf("\n%s\nIsErrorNotFound(...): -want, +got:\n%s\nerror: %v", tc.reason, diff, err)
+			}
+			if diff := cmp.Diff(tc.want.pet, got); diff != "" {
+				t.Errorf("\n%s\nc.GetPetById(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestUpdatePetById(t *testing.T) {
+	want := existingPet()
+	want.Status = PetStatusAvailable
+
+	got, err := newTestClient(t).UpdatePetById(context.Background(), "424242", want)
+	if err != nil {
+		t.Fatalf("c.UpdatePetById(...): %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("c.UpdatePetById(...): -want, +got:\n%s\n", diff)
+	}
+}
+
+func TestDeletePetById(t *testing.T) {
+	cases := map[string]struct {
+		reason       string
+		id           string
+		wantNotFound bool
+	}{
+		"Deleted": {
+			reason: "Deleting an existing pet should succeed.",
+			id:     "424242",
+		},
+		"NotFound": {
+			reason:       "Deleting a missing pet should be reported as a ResourceNotFoundException.",
+			id:           "404404",
+			wantNotFound: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := newTestClient(t).DeletePetById(context.Background(), tc.id)
+			if diff := cmp.Diff(tc.wantNotFound, petstore.IsErrorNotFound(err)); diff != "" {
+				t.Errorf("\n%s\nIsErrorNotFound(...): -want, +got:\n%s\nerror: %v", tc.reason, diff, err)
+			}
+			if !tc.wantNotFound && err != nil {
+				t.Errorf("\n%s\nc.DeletePetById(...): %v", tc.reason, err)
+			}
+		})
+	}
+}
+
+func TestFindPets(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		find   func(c *PetClient, visit func(*Pet) error) error
+		want   []*Pet
+	}{
+		"ByStatus": {
+			reason: "The pets having any of the statuses should be visited.",
+			find: func(c *PetClient, visit func(*Pet) error) error {
+				return c.FindPetsByStatus(context.Background(), []PetStatus{PetStatusPending, PetStatusAvailable}, visit)
+			},
+			want: []*Pet{existingPet()},
+		},
+		"ByStatusNone": {
+			reason: "No pet should be visited when none has the status.",
+			find: func(c *PetClient, visit func(*Pet) error) error {
+				return c.FindPetsByStatus(context.Background(), []PetStatus{PetStatusFailed}, visit)
+			},
+		},
+		"ByTags": {
+			reason: "The pets having the tag should be visited.",
+			find: func(c *PetClient, visit func(*Pet) error) error {
+				return c.FindPetsByTags(context.Background(), []string{"friendly"}, visit)
+			},
+			want: []*Pet{existingPet()},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var got []*Pet
+			err := tc.find(newTestClient(t), func(p *Pet) error {
+				got = append(got, p)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("\n%s\nfind pets: %v", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nfind pets: -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestUploadImage(t *testing.T) {
+	image := []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n', 0x00, 0xff}
+	got, err := newTestClient(t).UploadImage(context.Background(), "424242", "doggie.png", image, "front")
+	if err != nil {
+		t.Fatalf("c.UploadImage(...): %v", err)
+	}
+	want := &ApiResponse{Code: 200, Type: "unknown", Message: "additionalMetadata: front\nFile uploaded to ./doggie.png, 10 bytes"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("c.UploadImage(...): -want, +got:\n%s\n", diff)
+	}
+}
+
+func TestUpdatePetWithForm(t *testing.T) {
+	if err := newTestClient(t).UpdatePetWithForm(context.Background(), "424242", "doggie", PetStatusAvailable); err != nil {
+		t.Fatalf("c.UpdatePetWithForm(...): %v", err)
+	}
+}
+
+func TestPatchPet(t *testing.T) {
+	tags := []Tag{{Id: petstore.Int64(2), Name: petstore.String("vip")}}
+	patched := existingPet()
+	patched.Category.Name = petstore.String("hounds")
+	patched.Tags = &tags
+
+	cases := map[string]struct {
+		reason      string
+		patch       PetPatch
+		want        *Pet
+		unsupported bool
+	}{
+		"Patched": {
+			reason: "A merge patch should change the fields it holds, and merge nested objects, leaving the other fields as they are.",
+			patch: PetPatch{
+				Category: &Category{Name: petstore.String("hounds")},
+				Tags:     &tags,
+			},
+			want: patched,
+		},
+		"Unsupported": {
+			reason:      "A store that does not support PATCH should fail with an error for which IsErrorPatchUnsupported is true.",
+			patch:       PetPatch{Name: petstore.String("kitty")},
+			unsupported: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := newTestClient(t).PatchPet(context.Background(), "424242", tc.patch)
+			if diff := cmp.Diff(tc.unsupported, IsErrorPatchUnsupported(err)); diff != "" {
+				t.Errorf("\n%s\nIsErrorPatchUnsupported(...): -want, +got:\n%s\nerror: %v", tc.reason, diff, err)
+			}
+			if !tc.unsupported && err != nil {
+				t.Fatalf("\n%s\nc.PatchPet(...): %v", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nc.PatchPet(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestPetXML(t *testing.T) {
+	// The XML representation of a pet in the petstore specification.
+	const doc = `<Pet><category><id>1</id><name>dogs</name></category><id>424242</id><name>doggie</name>` +
+		`<photoUrls><photoUrl>https://example.com/doggie.png</photoUrl></photoUrls><status>PENDING</status>` +
+		`<tags><tag><id>1</id><name>friendly</name></tag></tags></Pet>`
+
+	want := existingPet()
+
+	got := &Pet{}
+	if err := xml.Unmarshal([]byte(doc), got); err != nil {
+		t.Fatalf("xml.Unmarshal(...): %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("xml.Unmarshal(...): -want, +got:\n%s\n", diff)
+	}
+
+	b, err := xml.Marshal(want)
+	if err != nil {
+		t.Fatalf("xml.Marshal(...): %v", err)
+	}
+	if diff := cmp.Diff(doc, string(b)); diff != "" {
+		t.Errorf("xml.Marshal(...): -want, +got:\n%s\n", diff)
+	}
+}
@@ -1,29 +1,65 @@
 package fake
 
-import clientset "github.com/alexisries/provider-petstore/internal/clients/pet"
+import (
+	"context"
+
+	clientset "github.com/alexisries/provider-petstore/internal/clients/pet"
+)
 
 // this ensures that the mock implements the client interface
 var _ clientset.Client = (*MockPetClient)(nil)
 
 type MockPetClient struct {
-	MockAddPet        func(pet *clientset.Pet) (*clientset.Pet, error)
-	MockGetPetById    func(petId string) (*clientset.Pet, error)
-	MockUpdatePetById func(petId string, pet *clientset.Pet) error
-	MockDeletePetById func(petId string) error
+	MockAddPet             func(ctx context.Context, pet *clientset.Pet, idempotencyKey string) (*clientset.Pet, error)
+	MockGetPetById         func(ctx context.Context, petId string) (*clientset.Pet, error)
+	MockGetPetByIdUncached func(ctx context.Context, petId string) (*clientset.Pet, error)
+	MockUpdatePetById      func(ctx context.Context, petId string, pet *clientset.Pet) (*clientset.Pet, error)
+	MockDeletePetById      func(ctx context.Context, petId string) error
+
+	MockPatchPet          func(ctx context.Context, petId string, patch clientset.PetPatch) (*clientset.Pet, error)
+	MockUpdatePetWithForm func(ctx context.Context, petId string, name string, status clientset.PetStatus) error
+
+	MockFindPetsByStatus func(ctx context.Context, status []clientset.PetStatus, visit func(*clientset.Pet) error) error
+	MockFindPetsByTags   func(ctx context.Context, tags []string, visit func(*clientset.Pet) error) error
+	MockUploadImage      func(ctx context.Context, petId string, fileName string, image []byte, additionalMetadata string) (*clientset.ApiResponse, error)
+}
+
+func (m *MockPetClient) AddPet(ctx context.Context, pet *clientset.Pet, idempotencyKey string) (*clientset.Pet, error) {
+	return m.MockAddPet(ctx, pet, idempotencyKey)
+}
+
+func (m *MockPetClient) GetPetById(ctx context.Context, petId string) (*clientset.Pet, error) {
+	return m.MockGetPetById(ctx, petId)
+}
+
+func (m *MockPetClient) GetPetByIdUncached(ctx context.Context, petId string) (*clientset.Pet, error) {
+	return m.MockGetPetByIdUncached(ctx, petId)
+}
+
+func (m *MockPetClient) UpdatePetById(ctx context.Context, petId string, pet *clientset.Pet) (*clientset.Pet, error) {
+	return m.MockUpdatePetById(ctx, petId, pet)
+}
+
+func (m *MockPetClient) PatchPet(ctx context.Context, petId string, patch clientset.PetPatch) (*clientset.Pet, error) {
+	return m.MockPatchPet(ctx, petId, patch)
+}
+
+func (m *MockPetClient) UpdatePetWithForm(ctx context.Context, petId string, name string, status clientset.PetStatus) error {
+	return m.MockUpdatePetWithForm(ctx, petId, name, status)
 }
 
-func (m *MockPetClient) AddPet(pet *clientset.Pet) (*clientset.Pet, error) {
-	return m.MockAddPet(pet)
+func (m *MockPetClient) DeletePetById(ctx context.Context, petId string) error {
+	return m.MockDeletePetById(ctx, petId)
 }
 
-func (m *MockPetClient) GetPetById(petId string) (*clientset.Pet, error) {
-	return m.MockGetPetById(petId)
+func (m *MockPetClient) FindPetsByStatus(ctx context.Context, status []clientset.PetStatus, visit func(*clientset.Pet) error) error {
+	return m.MockFindPetsByStatus(ctx, status, visit)
 }
 
-func (m *MockPetClient) UpdatePetById(petId string, pet *clientset.Pet) error {
-	return m.MockUpdatePetById(petId, pet)
+func (m *MockPetClient) FindPetsByTags(ctx context.Context, tags []string, visit func(*clientset.Pet) error) error {
+	return m.MockFindPetsByTags(ctx, tags, visit)
 }
 
-func (m *MockPetClient) DeletePetById(petId string) error {
-	return m.MockDeletePetById(petId)
+func (m *MockPetClient) UploadImage(ctx context.Context, petId string, fileName string, image []byte, additionalMetadata string) (*clientset.ApiResponse, error) {
+	return m.MockUploadImage(ctx, petId, fileName, image, additionalMetadata)
 }
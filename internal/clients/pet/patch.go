@@ -0,0 +1,228 @@
+package pet
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/alexisries/provider-petstore/apis/store/v1alpha1"
+	petstore "github.com/alexisries/provider-petstore/internal/clients"
+)
+
+// A PetPatch is a JSON merge patch of a pet, as defined by RFC 7396. It holds
+// the fields of a pet that differ from its parameters; the other fields are
+// omitted, so that the pet store leaves them as they are.
+type PetPatch struct {
+	Category  *Category `json:"category,omitempty"`
+	Name      *string   `json:"name,omitempty"`
+	PhotoUrls *[]string `json:"photoUrls,omitempty"`
+	Tags      *[]Tag    `json:"tags,omitempty"`
+}
+
+// GeneratePetPatch returns the patch that brings the supplied pet up to date
+// with the supplied parameters. The name of the pet is always managed, and its
+// category when the parameters set one. Photo URLs and tags are merged: those
+// of the parameters are added, and those that the supplied observation records
+// as last applied but that were since dropped from the parameters are removed.
+// The others were added by someone else and are kept. Tags are matched by ID.
+func GeneratePetPatch(p v1alpha1.PetParameters, o v1alpha1.PetObservation, cd *Pet) PetPatch {
+	patch := PetPatch{}
+	if p.Name != cd.Name {
+		patch.Name = petstore.String(p.Name)
+	}
+	if p.Category != nil && !isCategoryUptodate(*p.Category, cd.Category) {
+		patch.Category = &Category{
+			Id:   petstore.Int64(p.Category.Id),
+			Name: petstore.String(p.Category.Name),
+		}
+	}
+	if urls, changed := patchPhotoUrls(p.PhotoUrls, o.ManagedPhotoUrls, cd.PhotoUrls); changed {
+		patch.PhotoUrls = &urls
+	}
+	if tags, changed := patchTags(p.Tags, o.ManagedTagIds, cd.Tags); changed {
+		patch.Tags = &tags
+	}
+	return patch
+}
+
+// IsEmpty reports whether the patch leaves a pet as it is.
+func (p PetPatch) IsEmpty() bool {
+	return p.Category == nil && p.Name == nil && p.PhotoUrls == nil && p.Tags == nil
+}
+
+// IsNameOnly reports whether the patch sets the name of a pet and nothing
+// else, so that UpdatePetWithForm can apply it. It cannot clear names.
+func (p PetPatch) IsNameOnly() bool {
+	return p.Name != nil && *p.Name != "" && p.Category == nil && p.PhotoUrls == nil && p.Tags == nil
+}
+
+// Apply returns a copy of the supplied pet with the patch applied to it.
+func (p PetPatch) Apply(pet *Pet) *Pet {
+	patched := *pet
+	if p.Category != nil {
+		patched.Category = p.Category
+	}
+	if p.Name != nil {
+		patched.Name = *p.Name
+	}
+	if p.PhotoUrls != nil {
+		patched.PhotoUrls = *p.PhotoUrls
+	}
+	if p.Tags != nil {
+		patched.Tags = p.Tags
+	}
+	return &patched
+}
+
+func isCategoryUptodate(p v1alpha1.PetCategory, cd *Category) bool {
+	return cd != nil &&
+		cd.Id != nil && *cd.Id == p.Id &&
+		cd.Name != nil && *cd.Name == p.Name
+}
+
+// patchPhotoUrls returns the supplied current photo URLs without those last
+// applied but no longer in the supplied parameters, and with the missing ones
+// of the parameters appended, and whether they differ from the current ones.
+func patchPhotoUrls(spec, applied, current []string) ([]string, bool) {
+	wanted := make(map[string]bool, len(spec))
+	for _, u := range spec {
+		wanted[u] = true
+	}
+	dropped := map[string]bool{}
+	for _, u := range applied {
+		dropped[u] = !wanted[u]
+	}
+
+	urls := make([]string, 0, len(current)+len(spec))
+	changed := false
+	present := map[string]bool{}
+	for _, u := range current {
+		if dropped[u] {
+			changed = true
+			continue
+		}
+		present[u] = true
+		urls = append(urls, u)
+	}
+	for _, u := range spec {
+		if !present[u] {
+			present[u] = true
+			changed = true
+			urls = append(urls, u)
+		}
+	}
+	return urls, changed
+}
+
+// patchTags returns the supplied current tags with the tags of the supplied
+// parameters set, and whether they differ from the current tags. Managed tags
+// already present are renamed in place and appear only once, missing ones are
+// appended, and those last applied but no longer in the parameters are
+// removed.
+func patchTags(spec []v1alpha1.PetTag, applied []int64, current *[]Tag) ([]Tag, bool) {
+	if len(spec) == 0 && len(applied) == 0 {
+		return nil, false
+	}
+	wanted := make(map[int64]string, len(spec))
+	for _, t := range spec {
+		wanted[t.Id] = t.Name
+	}
+	dropped := map[int64]bool{}
+	for _, id := range applied {
+		_, ok := wanted[id]
+		dropped[id] = !ok
+	}
+
+	tags := make([]Tag, 0, len(derefTags(current))+len(spec))
+	changed := false
+	present := map[int64]bool{}
+	for _, t := range derefTags(current) {
+		keep, renamed := patchTag(t, wanted, dropped, present)
+		if keep != nil {
+			tags = append(tags, *keep)
+		}
+		changed = changed || keep == nil || renamed
+	}
+	for _, t := range spec {
+		if !present[t.Id] {
+			present[t.Id] = true
+			changed = true
+			tags = append(tags, Tag{Id: petstore.Int64(t.Id), Name: petstore.String(t.Name)})
+		}
+	}
+	return tags, changed
+}
+
+// patchTag returns the supplied current tag as it should be kept, or nil if
+// it should be removed, and whether it was renamed. Managed tags are recorded
+// as present.
+func patchTag(t Tag, wanted map[int64]string, dropped, present map[int64]bool) (*Tag, bool) {
+	if t.Id == nil {
+		return &t, false
+	}
+	name, ok := wanted[*t.Id]
+	switch {
+	case dropped[*t.Id], ok && present[*t.Id]:
+		return nil, false
+	case !ok:
+		return &t, false
+	}
+	present[*t.Id] = true
+	return &Tag{Id: petstore.Int64(*t.Id), Name: petstore.String(name)}, t.Name == nil || *t.Name != name
+}
+
+func derefTags(tags *[]Tag) []Tag {
+	if tags == nil {
+		return nil
+	}
+	return *tags
+}
+
+// IsErrorPatchUnsupported reports whether the supplied error shows that the
+// pet store does not support JSON merge patches of pets.
+func IsErrorPatchUnsupported(err error) bool {
+	code, ok := petstore.StatusCode(err)
+	if !ok {
+		return false
+	}
+	switch code {
+	case http.StatusMethodNotAllowed, http.StatusUnsupportedMediaType, http.StatusNotImplemented:
+		return true
+	}
+	return false
+}
+
+// A PatchSupport remembers the pet stores, keyed by server URL, that do not
+// support JSON merge patches of pets, so that their pets are updated without
+// trying to patch them first. A nil PatchSupport assumes every store does.
+type PatchSupport struct {
+	mu          sync.Mutex
+	unsupported map[string]bool
+}
+
+// NewPatchSupport returns a PatchSupport that assumes every store supports
+// JSON merge patches until told otherwise.
+func NewPatchSupport() *PatchSupport {
+	return &PatchSupport{unsupported: map[string]bool{}}
+}
+
+// Supported reports whether the pet store with the supplied server URL may
+// support JSON merge patches.
+func (s *PatchSupport) Supported(server string) bool {
+	if s == nil {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.unsupported[server]
+}
+
+// SetUnsupported records that the pet store with the supplied server URL does
+// not support JSON merge patches.
+func (s *PatchSupport) SetUnsupported(server string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unsupported[server] = true
+}
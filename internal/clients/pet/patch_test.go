@@ -0,0 +1,189 @@
+package pet
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/alexisries/provider-petstore/apis/store/v1alpha1"
+	petstore "github.com/alexisries/provider-petstore/internal/clients"
+)
+
+func tag(id int64, name string) Tag {
+	return Tag{Id: petstore.Int64(id), Name: petstore.String(name)}
+}
+
+func TestGeneratePetPatch(t *testing.T) {
+	params := v1alpha1.PetParameters{
+		Name:      "doggie",
+		Category:  &v1alpha1.PetCategory{Id: 1, Name: "dogs"},
+		Tags:      []v1alpha1.PetTag{{Id: 1, Name: "friendly"}},
+		PhotoUrls: []string{"https://example.com/doggie.png", "https://example.com/puppy.png"},
+	}
+	applied := v1alpha1.PetObservation{
+		ManagedTagIds:    []int64{1},
+		ManagedPhotoUrls: params.PhotoUrls,
+	}
+
+	cases := map[string]struct {
+		reason  string
+		params  v1alpha1.PetParameters
+		applied v1alpha1.PetObservation
+		pet     func(p *Pet)
+		want    PetPatch
+	}{
+		"UpToDate": {
+			reason:  "A pet whose managed fields match should not be patched, whatever the order of its photos.",
+			params:  params,
+			applied: applied,
+			pet: func(p *Pet) {
+				p.PhotoUrls = []string{"https://example.com/puppy.png", "https://example.com/doggie.png"}
+			},
+		},
+		"NeverApplied": {
+			reason: "Photos and tags never applied should not be removed, nor a category the parameters do not set.",
+			params: v1alpha1.PetParameters{Name: "doggie"},
+		},
+		"ForeignKept": {
+			reason:  "Photos and tags added by someone else should neither be patched nor removed.",
+			params:  params,
+			applied: applied,
+			pet: func(p *Pet) {
+				p.Tags = &[]Tag{tag(2, "vip"), tag(1, "friendly")}
+				p.PhotoUrls = append(p.PhotoUrls, "https://example.com/vip.png")
+			},
+		},
+		"Name": {
+			reason:  "A drifted name should be patched alone.",
+			params:  params,
+			applied: applied,
+			pet:     func(p *Pet) { p.Name = "kitty" },
+			want:    PetPatch{Name: petstore.String("doggie")},
+		},
+		"NameCleared": {
+			reason: "A name cleared from the parameters should be cleared.",
+			params: v1alpha1.PetParameters{},
+			want:   PetPatch{Name: petstore.String("")},
+		},
+		"Category": {
+			reason:  "A missing category should be patched.",
+			params:  params,
+			applied: applied,
+			pet:     func(p *Pet) { p.Category = nil },
+			want:    PetPatch{Category: &Category{Id: petstore.Int64(1), Name: petstore.String("dogs")}},
+		},
+		"PhotoUrlAdded": {
+			reason:  "A photo missing from the pet should be appended to its photos.",
+			params:  params,
+			applied: applied,
+			pet: func(p *Pet) {
+				p.PhotoUrls = []string{"https://example.com/vip.png", "https://example.com/doggie.png"}
+			},
+			want: PetPatch{PhotoUrls: &[]string{
+				"https://example.com/vip.png", "https://example.com/doggie.png", "https://example.com/puppy.png"}},
+		},
+		"PhotoUrlRemoved": {
+			reason:  "A photo dropped from the parameters should be removed, keeping photos added by someone else.",
+			params:  v1alpha1.PetParameters{Name: "doggie", PhotoUrls: []string{"https://example.com/doggie.png"}},
+			applied: v1alpha1.PetObservation{ManagedPhotoUrls: params.PhotoUrls},
+			pet: func(p *Pet) {
+				p.PhotoUrls = append(p.PhotoUrls, "https://example.com/vip.png")
+			},
+			want: PetPatch{PhotoUrls: &[]string{"https://example.com/doggie.png", "https://example.com/vip.png"}},
+		},
+		"PhotoUrlsCleared": {
+			reason:  "Photos cleared from the parameters should be removed.",
+			params:  v1alpha1.PetParameters{Name: "doggie"},
+			applied: v1alpha1.PetObservation{ManagedPhotoUrls: params.PhotoUrls},
+			want:    PetPatch{PhotoUrls: &[]string{}},
+		},
+		"Tags": {
+			reason:  "Renamed managed tags should be fixed in place and duplicates dropped, keeping foreign tags.",
+			params:  v1alpha1.PetParameters{Name: "doggie", Tags: []v1alpha1.PetTag{{Id: 1, Name: "friendly"}, {Id: 3, Name: "small"}}},
+			applied: v1alpha1.PetObservation{ManagedTagIds: []int64{1}},
+			pet: func(p *Pet) {
+				p.Tags = &[]Tag{tag(1, "grumpy"), tag(2, "vip"), tag(1, "friendly"), {Name: petstore.String("untagged")}}
+			},
+			want: PetPatch{Tags: &[]Tag{tag(1, "friendly"), tag(2, "vip"), {Name: petstore.String("untagged")}, tag(3, "small")}},
+		},
+		"TagRemoved": {
+			reason:  "A tag dropped from the parameters should be removed, keeping tags added by someone else.",
+			params:  v1alpha1.PetParameters{Name: "doggie", Tags: []v1alpha1.PetTag{{Id: 1, Name: "friendly"}}},
+			applied: v1alpha1.PetObservation{ManagedTagIds: []int64{1, 3}},
+			pet: func(p *Pet) {
+				p.Tags = &[]Tag{tag(1, "friendly"), tag(2, "vip"), tag(3, "small")}
+			},
+			want: PetPatch{Tags: &[]Tag{tag(1, "friendly"), tag(2, "vip")}},
+		},
+		"TagsCleared": {
+			reason:  "Tags cleared from the parameters should be removed.",
+			params:  v1alpha1.PetParameters{Name: "doggie"},
+			applied: v1alpha1.PetObservation{ManagedTagIds: []int64{1}},
+			want:    PetPatch{Tags: &[]Tag{}},
+		},
+		"TagsMissing": {
+			reason:  "Managed tags should be added to a pet without tags.",
+			params:  params,
+			applied: applied,
+			pet:     func(p *Pet) { p.Tags = nil },
+			want:    PetPatch{Tags: &[]Tag{tag(1, "friendly")}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			pet := testPet()
+			pet.PhotoUrls = append(pet.PhotoUrls, "https://example.com/puppy.png")
+			if tc.pet != nil {
+				tc.pet(pet)
+			}
+			got := GeneratePetPatch(tc.params, tc.applied, pet)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nGeneratePetPatch(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+			if IsPetUptodate(tc.params, tc.applied, pet) != got.IsEmpty() {
+				t.Errorf("\n%s\nIsPetUptodate(...): want %t", tc.reason, got.IsEmpty())
+			}
+		})
+	}
+}
+
+func TestPetPatchIsNameOnly(t *testing.T) {
+	cases := map[string]struct {
+		patch PetPatch
+		want  bool
+	}{
+		"Name":        {patch: PetPatch{Name: petstore.String("kitty")}, want: true},
+		"NameCleared": {patch: PetPatch{Name: petstore.String("")}},
+		"NameAndTags": {patch: PetPatch{Name: petstore.String("kitty"), Tags: &[]Tag{}}},
+		"Empty":       {patch: PetPatch{}},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := tc.patch.IsNameOnly(); got != tc.want {
+				t.Errorf("IsNameOnly(): want %t, got %t", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestPetPatchApply(t *testing.T) {
+	pet := testPet()
+	pet.Id = petstore.Int64(testPetID)
+	pet.Status = PetStatusAvailable
+	tags := []Tag{tag(1, "friendly"), tag(2, "vip")}
+
+	got := PetPatch{Name: petstore.String("kitty"), Tags: &tags}.Apply(pet)
+
+	want := testPet()
+	want.Id = petstore.Int64(testPetID)
+	want.Status = PetStatusAvailable
+	want.Name = "kitty"
+	want.Tags = &tags
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Apply(...): -want, +got:\n%s\n", diff)
+	}
+	if pet.Name != "doggie" {
+		t.Errorf("Apply(...): the supplied pet should not be modified")
+	}
+}
@@ -1,15 +1,23 @@
 package pet
 
 import (
+	"context"
+
 	"github.com/alexisries/provider-petstore/apis/store/v1alpha1"
 	petstore "github.com/alexisries/provider-petstore/internal/clients"
 )
 
 type Client interface {
-	AddPet(pet *Pet) (*Pet, error)
-	GetPetById(petId string) (*Pet, error)
-	UpdatePetById(petId string, pet *Pet) error
-	DeletePetById(petId string) error
+	AddPet(ctx context.Context, pet *Pet, idempotencyKey string) (*Pet, error)
+	GetPetById(ctx context.Context, petId string) (*Pet, error)
+	GetPetByIdUncached(ctx context.Context, petId string) (*Pet, error)
+	UpdatePetById(ctx context.Context, petId string, pet *Pet) (*Pet, error)
+	PatchPet(ctx context.Context, petId string, patch PetPatch) (*Pet, error)
+	UpdatePetWithForm(ctx context.Context, petId string, name string, status PetStatus) error
+	DeletePetById(ctx context.Context, petId string) error
+	FindPetsByStatus(ctx context.Context, status []PetStatus, visit func(*Pet) error) error
+	FindPetsByTags(ctx context.Context, tags []string, visit func(*Pet) error) error
+	UploadImage(ctx context.Context, petId string, fileName string, image []byte, additionalMetadata string) (*ApiResponse, error)
 }
 
 func NewClient(cfg *petstore.Config) Client {
@@ -17,11 +25,16 @@ func NewClient(cfg *petstore.Config) Client {
 	return &cl
 }
 
+// GeneratePetStatus returns the observation of the supplied pet. A pet the
+// store returned without an ID is observed with ID 0.
 func GeneratePetStatus(pet *Pet) v1alpha1.PetObservation {
-	return v1alpha1.PetObservation{
-		Id:     *pet.Id,
+	o := v1alpha1.PetObservation{
 		Status: string(pet.Status),
 	}
+	if pet.Id != nil {
+		o.Id = *pet.Id
+	}
+	return o
 }
 
 func GeneratePet(p v1alpha1.PetParameters) *Pet {
@@ -47,98 +60,9 @@ func GeneratePet(p v1alpha1.PetParameters) *Pet {
 	return pet
 }
 
-func IsPetUptodate(p v1alpha1.PetParameters, cd *Pet) bool {
-	switch {
-	case p.Name != cd.Name:
-		return false
-	case p.Category != nil && cd.Category == nil:
-		return false
-	case p.Category != nil && cd.Category != nil &&
-		(p.Category.Name != *cd.Category.Name ||
-			p.Category.Id != *cd.Category.Id):
-		return false
-	case !IsTagsUptodate(p, cd):
-		return false
-	case !IsPhotosUrlUptodate(p, cd):
-		return false
-	}
-	return true
-}
-
-func IsTagsUptodate(p v1alpha1.PetParameters, cd *Pet) bool {
-	if (p.Tags != nil && cd.Tags == nil) ||
-		(p.Tags == nil && cd.Tags != nil) {
-		return false
-	}
-	if p.Tags != nil && cd.Tags != nil {
-		tagsAdd, tagsRemove := DiffTags(p.Tags, *cd.Tags)
-		if len(tagsAdd) != 0 || len(tagsRemove) != 0 {
-			return false
-		}
-	}
-	return true
-}
-
-func IsPhotosUrlUptodate(p v1alpha1.PetParameters, cd *Pet) bool {
-	if (p.PhotoUrls != nil && cd.PhotoUrls == nil) ||
-		(p.PhotoUrls == nil && cd.PhotoUrls != nil) {
-		return false
-	}
-	if p.Tags != nil && cd.Tags != nil {
-		photosAdd, photosRemove := DiffPhotos(p.PhotoUrls, cd.PhotoUrls)
-		if len(photosAdd) != 0 || len(photosRemove) != 0 {
-			return false
-		}
-	}
-	return true
-}
-
-func DiffPhotos(spec []string, current []string) (addPhotoUrls []string, remove []string) {
-	for _, specPhotoUrl := range spec {
-		found := false
-		for _, currentPhotoUrl := range current {
-			if specPhotoUrl == currentPhotoUrl {
-				found = true
-				break
-			}
-		}
-		if found {
-			addPhotoUrls = append(addPhotoUrls, specPhotoUrl)
-		}
-	}
-	for _, currentPhotoUrl := range spec {
-		found := false
-		for _, specPhotoUrl := range current {
-			if currentPhotoUrl == specPhotoUrl {
-				found = true
-				break
-			}
-		}
-		if found {
-			remove = append(remove, currentPhotoUrl)
-		}
-	}
-	return
-}
-
-func DiffTags(spec []v1alpha1.PetTag, current []Tag) (addTags []Tag, remove []Tag) {
-	addMap := make(map[int64]string, len(spec))
-	for _, t := range spec {
-		addMap[t.Id] = t.Name
-	}
-	removeMap := map[int64]string{}
-	for _, t := range current {
-		if addMap[*t.Id] == *t.Name {
-			delete(addMap, *t.Id)
-			continue
-		}
-		removeMap[*t.Id] = *t.Name
-	}
-	for k, v := range addMap {
-		addTags = append(addTags, Tag{Id: petstore.Int64(k), Name: petstore.String(v)})
-	}
-	for k, v := range removeMap {
-		remove = append(remove, Tag{Id: petstore.Int64(k), Name: petstore.String(v)})
-	}
-	return
+// IsPetUptodate reports whether the fields of the supplied pet that the
+// supplied parameters manage are up to date with them, given the supplied
+// observation of what was last applied.
+func IsPetUptodate(p v1alpha1.PetParameters, o v1alpha1.PetObservation, cd *Pet) bool {
+	return GeneratePetPatch(p, o, cd).IsEmpty()
 }
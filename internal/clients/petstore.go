@@ -3,110 +3,514 @@ package petstore
 import (
 	"bytes"
 	"context"
-	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
 )
 
-type ResourceNotFoundException struct {
-	Message *string
+// apiKeyHeader is the header the pet store reads its API key from.
+const apiKeyHeader = "api_key"
+
+type Config struct {
+	server        *url.URL
+	serverErr     error
+	apiKey        string
+	headers       http.Header
+	tokenSource   *TokenSource
+	httpClient    *http.Client
+	retry         RetryPolicy
+	retryNotifier RetryNotifier
+	observer      RequestObserver
+	limiter       *Limiter
+	breaker       *CircuitBreaker
+	cache         *ResponseCache
+	format        Format
+	maxResponse   int64
+	unknownFields UnknownFieldsNotifier
+
+	logger         logging.Logger
+	dumpBodies     bool
+	redactedValues []string
+}
+
+// A ConfigOption configures a pet store client.
+type ConfigOption func(*Config)
+
+// WithHTTPClient sets the http.Client used to send requests to the pet store.
+func WithHTTPClient(hc *http.Client) ConfigOption {
+	return func(c *Config) {
+		c.httpClient = hc
+	}
 }
 
-func (e *ResourceNotFoundException) Error() string {
-	return fmt.Sprintf("%s: %s", e.ErrorCode(), e.ErrorMessage())
+// WithAPIKey sets the API key sent with every request to the pet store.
+func WithAPIKey(key string) ConfigOption {
+	return func(c *Config) {
+		c.apiKey = key
+	}
 }
-func (e *ResourceNotFoundException) ErrorCode() string { return "ResourceNotFoundException" }
-func (e *ResourceNotFoundException) ErrorMessage() string {
-	if e.Message == nil {
-		return ""
+
+// WithHeaders sets static headers sent with every request to the pet store.
+// They cannot override the headers the client sets itself, such as the API
+// key or the content type.
+func WithHeaders(h map[string]string) ConfigOption {
+	return func(c *Config) {
+		c.headers = make(http.Header, len(h))
+		for k, v := range h {
+			c.headers.Set(k, v)
+		}
 	}
-	return *e.Message
 }
 
-func errorFromStatusCode(statusCode int, message string) (err error) {
-	switch statusCode {
-	case 404:
-		err = &ResourceNotFoundException{Message: &message}
-	default:
-		err = errors.New(message)
+// WithTokenSource authenticates every request to the pet store with an OAuth2
+// access token obtained from the supplied TokenSource.
+func WithTokenSource(ts *TokenSource) ConfigOption {
+	return func(c *Config) {
+		c.tokenSource = ts
 	}
-	return err
 }
 
-func IsErrorNotFound(err error) bool {
-	var notFoundError *ResourceNotFoundException
-	return errors.As(err, &notFoundError)
+// WithRetryPolicy sets how failed requests to the pet store are retried.
+func WithRetryPolicy(p RetryPolicy) ConfigOption {
+	return func(c *Config) {
+		c.retry = p
+	}
 }
 
-type Config struct {
-	server string
+// WithRetryNotifier sets a function that is called before each retry, for
+// example to log or record an event about it.
+func WithRetryNotifier(fn RetryNotifier) ConfigOption {
+	return func(c *Config) {
+		c.retryNotifier = fn
+	}
+}
+
+// WithLimiter bounds the rate and concurrency of requests to the pet store.
+// Requests wait for the Limiter before being sent.
+func WithLimiter(l *Limiter) ConfigOption {
+	return func(c *Config) {
+		c.limiter = l
+	}
+}
+
+// WithCircuitBreaker stops sending requests to the pet store while the
+// supplied CircuitBreaker is open.
+func WithCircuitBreaker(b *CircuitBreaker) ConfigOption {
+	return func(c *Config) {
+		c.breaker = b
+	}
+}
+
+// WithFormat sets the media type of the bodies exchanged with the pet store.
+func WithFormat(f Format) ConfigOption {
+	return func(c *Config) {
+		c.format = f
+	}
+}
+
+// WithMaxResponseSize bounds the size of the bodies of the responses of the
+// pet store. Reading beyond the supplied number of bytes fails with a
+// DecodeError. Defaults to 1MiB.
+func WithMaxResponseSize(n int64) ConfigOption {
+	return func(c *Config) {
+		c.maxResponse = n
+	}
+}
+
+// WithStrictDecoding reports the fields of decoded JSON responses that have
+// no counterpart in the type they are decoded into to the supplied function.
+func WithStrictDecoding(fn UnknownFieldsNotifier) ConfigOption {
+	return func(c *Config) {
+		c.unknownFields = fn
+	}
+}
+
+// WithResponseCache serves GET requests from the supplied ResponseCache when
+// possible, and revalidates the responses it holds with conditional requests.
+func WithResponseCache(rc *ResponseCache) ConfigOption {
+	return func(c *Config) {
+		c.cache = rc
+	}
+}
+
+// WithRequestObserver sets a function that is called after every attempt at a
+// request, for example to record metrics about it.
+func WithRequestObserver(fn RequestObserver) ConfigOption {
+	return func(c *Config) {
+		c.observer = fn
+	}
+}
+
+// WithLogger sets the logger requests to the pet store are logged to at
+// debug level.
+func WithLogger(l logging.Logger) ConfigOption {
+	return func(c *Config) {
+		c.logger = l
+	}
 }
 
-func GetConfig(url string) *Config {
-	return &Config{
-		server: url,
+// WithBodyDump enables logging the headers and bodies of requests and
+// responses at debug level. Credentials are redacted.
+func WithBodyDump(enabled bool) ConfigOption {
+	return func(c *Config) {
+		c.dumpBodies = enabled
 	}
 }
 
+// WithRedactedValues sets secret values, such as headers read from Secrets,
+// that are redacted wherever they would otherwise be logged. The API key and
+// access tokens are always redacted.
+func WithRedactedValues(v ...string) ConfigOption {
+	return func(c *Config) {
+		c.redactedValues = append(c.redactedValues, v...)
+	}
+}
+
+// GetConfig returns the configuration of a client of the pet store at the
+// supplied URL. If the URL is invalid, every request fails with a URLError.
+func GetConfig(server string, opts ...ConfigOption) *Config {
+	cfg := &Config{
+		logger: logging.NewNopLogger(),
+	}
+	cfg.server, cfg.serverErr = ParseServerURL(server)
+	for _, o := range opts {
+		o(cfg)
+	}
+	return cfg
+}
+
 type Client struct {
-	config  *Config
-	context context.Context
+	config     *Config
+	httpClient *http.Client
 }
 
 func New(cfg *Config) *Client {
+	hc := cfg.httpClient
+	if hc == nil {
+		// Without TLS options building a client cannot fail.
+		hc, _ = NewHTTPClient(TransportOptions{})
+	}
 	return &Client{
-		config:  cfg,
-		context: context.TODO(),
+		config:     cfg,
+		httpClient: hc,
 	}
 }
 
-func (c *Client) prepareRequest(path string, method string, body []byte) (*http.Request, error) {
-	queryURL := c.config.server + path
+func (c *Client) prepareRequest(ctx context.Context, path string, method string, body []byte, header http.Header) (*http.Request, error) {
+	queryURL := c.endpoint(path)
 	var bodyReader io.Reader
 	if body != nil {
 		bodyReader = bytes.NewReader(body)
 	}
 
-	req, err := http.NewRequestWithContext(c.context, method, queryURL, bodyReader)
+	req, err := http.NewRequestWithContext(ctx, method, queryURL, bodyReader)
 	if err != nil {
 		return nil, err
 	}
 
+	for k, v := range c.config.headers {
+		req.Header[k] = append([]string(nil), v...)
+	}
+	for k, v := range header {
+		req.Header[k] = append([]string(nil), v...)
+	}
+	injectTraceContext(ctx, req)
 	switch method {
 	case "GET":
-		req.Header.Set("Accept", "application/json")
+		req.Header.Set("Accept", c.config.format.accept())
 	case "PUT", "POST":
-		req.Header.Set("Content-Type", "application/json")
+		// Only the caller may override the content type of the body, not
+		// static headers.
+		contentType := header.Get("Content-Type")
+		if contentType == "" {
+			contentType = c.config.format.contentType()
+		}
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.config.apiKey != "" {
+		req.Header.Set(apiKeyHeader, c.config.apiKey)
 	}
 
 	return req, nil
 }
 
-func (c *Client) DoRequest(path string, method string, body []byte) (*http.Response, error) {
-	req, err := c.prepareRequest(path, method, body)
+// DoRequest sends a request to the pet store. The request is bound to ctx, so
+// it is aborted as soon as ctx is cancelled or its deadline is exceeded.
+// Transient failures are retried according to the configured RetryPolicy.
+// Each call is recorded as a span that is a child of the span of ctx, if any.
+// While the circuit of the pet store is open, a StoreUnavailableError is
+// returned without sending the request. When a ResponseCache is configured,
+// GET requests may be answered from it.
+func (c *Client) DoRequest(ctx context.Context, path string, method string, body []byte) (*http.Response, error) {
+	return c.DoRequestWithHeader(ctx, path, method, body, nil)
+}
+
+// DoRequestWithHeader sends a request to the pet store like DoRequest, with
+// the supplied headers in addition to the static ones.
+func (c *Client) DoRequestWithHeader(ctx context.Context, path string, method string, body []byte, header http.Header) (*http.Response, error) {
+	ctx, span := startSpan(ctx, method, path)
+	res, err := c.cachedRequest(ctx, path, method, body, header)
+	endSpan(span, res, err)
+	return res, err
+}
+
+// streamingKey marks the context of a request whose response is streamed.
+type streamingKey struct{}
+
+// StreamRequest sends a GET request to the pet store like DoRequestWithHeader,
+// for a response whose body is read as it arrives rather than at once. The
+// response is never cached. The request timeout of the HTTP client bounds the
+// wait for its headers, but not reading its body, which only ctx bounds.
+func (c *Client) StreamRequest(ctx context.Context, path string, header http.Header) (*http.Response, error) {
+	header = header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Set("Cache-Control", "no-store")
+	return c.DoRequestWithHeader(context.WithValue(ctx, streamingKey{}, true), path, http.MethodGet, nil, header)
+}
+
+// InvalidateCache forgets the response cached for the supplied path, for
+// example after a request to another path changed the resource it returns.
+func (c *Client) InvalidateCache(path string) {
+	c.config.cache.Invalidate(c.endpoint(path))
+}
+
+// cachedRequest answers GET requests from the ResponseCache when its response
+// is fresh, and revalidates it otherwise or when the request carries
+// Cache-Control: no-cache. Any other request invalidates the cached response
+// of its path.
+func (c *Client) cachedRequest(ctx context.Context, path string, method string, body []byte, header http.Header) (*http.Response, error) {
+	if c.config.serverErr != nil {
+		return nil, c.config.serverErr
+	}
+	cache, key := c.config.cache, c.endpoint(path)
+	if method != http.MethodGet {
+		res, err := c.guardedRequest(ctx, path, method, body, header)
+		cache.Invalidate(key)
+		return res, err
+	}
+	if strings.Contains(header.Get("Cache-Control"), "no-store") {
+		// The caller does not want the response cached, for example
+		// because it is too large to be held in memory.
+		return c.guardedRequest(ctx, path, method, nil, header)
+	}
+
+	cached, ok := cache.lookup(key)
+	if ok && cache.fresh(cached) && !strings.Contains(header.Get("Cache-Control"), "no-cache") {
+		c.config.logger.Debug("Pet store response served from cache", "method", method, "path", path)
+		req, err := http.NewRequestWithContext(ctx, method, key, nil)
+		if err != nil {
+			return nil, err
+		}
+		return cached.response(req), nil
+	}
+	if ok {
+		header = header.Clone()
+		if header == nil {
+			header = http.Header{}
+		}
+		for k, v := range cached.conditionalHeader() {
+			header[k] = v
+		}
+	}
+	res, err := c.guardedRequest(ctx, path, method, nil, header)
+	switch {
+	case IsErrorNotFound(err):
+		cache.Invalidate(key)
+		return nil, err
+	case err != nil:
+		return nil, err
+	case ok && res.StatusCode == http.StatusNotModified:
+		discardBody(res)
+		cache.revalidate(key)
+		return cached.response(res.Request), nil
+	}
+	return cache.store(key, res)
+}
+
+// guardedRequest sends a request unless the circuit of the pet store is open.
+func (c *Client) guardedRequest(ctx context.Context, path string, method string, body []byte, header http.Header) (*http.Response, error) {
+	if err := c.config.breaker.allow(); err != nil {
+		return nil, err
+	}
+	res, err := c.doRequest(ctx, path, method, body, header)
+	c.config.breaker.record(ctx, err)
+	if err != nil {
+		return nil, err
+	}
+	res.Body = &limitedBody{ReadCloser: res.Body, method: method, path: path, limit: c.config.maxResponseSize()}
+	return res, nil
+}
+
+func (c *Client) doRequest(ctx context.Context, path string, method string, body []byte, header http.Header) (*http.Response, error) {
+	policy := c.config.retry.withDefaults()
+	for attempt := 0; ; attempt++ {
+		release, throttled, err := c.config.limiter.wait(ctx)
+		if err != nil {
+			return nil, err
+		}
+		start := time.Now()
+		res, err := c.send(ctx, path, method, body, header)
+		if res != nil {
+			res.Body = &releasingBody{ReadCloser: res.Body, release: release}
+		} else {
+			release()
+		}
+		c.observe(ctx, Request{Method: method, Attempt: attempt, Err: err, Latency: time.Since(start), Throttled: throttled}, path, res)
+		if attempt >= policy.MaxRetries || ctx.Err() != nil || !retryable(method, header, res, err) {
+			return handleResponse(method, path, res, err, c.config.maxResponseSize())
+		}
+
+		wait := policy.backoff(attempt)
+		if d, ok := retryAfter(res, time.Now()); ok {
+			if d > policy.MaxBackoff {
+				// The store asked us to back off for longer than we are
+				// willing to block a reconcile; let it be requeued instead.
+				return handleResponse(method, path, res, err, c.config.maxResponseSize())
+			}
+			wait = d
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			// Waiting would outlast the context; return the last error
+			// rather than one telling only that the deadline was exceeded.
+			return handleResponse(method, path, res, err, c.config.maxResponseSize())
+		}
+
+		a := RetryAttempt{Method: method, Path: path, Attempt: attempt + 1, Err: err, Wait: wait}
+		if res != nil {
+			a.StatusCode = res.StatusCode
+			discardBody(res)
+		}
+		recordRetry(ctx, a)
+		if c.config.retryNotifier != nil {
+			c.config.retryNotifier(ctx, a)
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// send sends a request once. When OAuth2 is configured and the pet store
+// rejects the access token, a new token is requested and the request is sent
+// one more time.
+func (c *Client) send(ctx context.Context, path string, method string, body []byte, header http.Header) (*http.Response, error) {
+	res, token, err := c.sendOnce(ctx, path, method, body, header)
+	if err != nil || res.StatusCode != http.StatusUnauthorized || c.config.tokenSource == nil {
+		return res, err
+	}
+	discardBody(res)
+	c.config.tokenSource.Invalidate(token)
+	res, _, err = c.sendOnce(ctx, path, method, body, header)
+	return res, err
+}
+
+func (c *Client) sendOnce(ctx context.Context, path string, method string, body []byte, header http.Header) (*http.Response, string, error) {
+	req, err := c.prepareRequest(ctx, path, method, body, header)
+	if err != nil {
+		return nil, "", err
+	}
+	var token string
+	if c.config.tokenSource != nil {
+		if token, err = c.config.tokenSource.Token(ctx); err != nil {
+			return nil, "", err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if c.config.dumpBodies {
+		c.dumpRequest(req, body, token)
+	}
+	var res *http.Response
+	if streaming, _ := ctx.Value(streamingKey{}).(bool); streaming {
+		res, err = doStreaming(c.httpClient, req)
+	} else {
+		res, err = c.httpClient.Do(req)
+	}
 	if err != nil {
+		return nil, token, wrapTLSError(err)
+	}
+	if c.config.dumpBodies {
+		c.dumpResponse(req, res, token)
+	}
+	return res, token, nil
+}
+
+// doStreaming sends a request with the supplied client, applying its timeout
+// to the wait for the response headers only. The body of the response can then
+// be read for as long as the context of the request allows.
+func doStreaming(hc *http.Client, req *http.Request) (*http.Response, error) {
+	timeout := hc.Timeout
+	if timeout <= 0 {
+		return hc.Do(req)
+	}
+	streaming := *hc
+	streaming.Timeout = 0
+	ctx, cancel := context.WithCancel(req.Context())
+	timer := time.AfterFunc(timeout, cancel)
+	res, err := streaming.Do(req.WithContext(ctx))
+	if !timer.Stop() {
+		cancel()
+		if err == nil {
+			res.Body.Close()
+		}
+		return nil, fmt.Errorf("%s %s: no response within %s: %w", req.Method, req.URL.Redacted(), timeout, context.DeadlineExceeded)
+	}
+	if err != nil {
+		cancel()
 		return nil, err
 	}
-	fmt.Println(req.URL)
-	res, err := http.DefaultClient.Do(req)
+	res.Body = &cancelingBody{ReadCloser: res.Body, cancel: cancel}
+	return res, nil
+}
+
+// A cancelingBody cancels the context of its request once closed.
+type cancelingBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// handleResponse turns a response that did not succeed into an error. At most
+// limit bytes of its body are read, and at most maxErrorMessage of them are
+// quoted in the error.
+func handleResponse(method string, path string, res *http.Response, err error, limit int64) (*http.Response, error) {
 	if err != nil {
 		return nil, err
 	}
-	if res.StatusCode != 200 {
+	// A 304 is only ever the answer to a conditional request, sent when the
+	// response is cached.
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusNotModified {
 		var errMsg string
 		var err error
 		defer res.Body.Close()
-		resBody, err := io.ReadAll(res.Body)
+		resBody, err := io.ReadAll(io.LimitReader(res.Body, limit))
 		if err == nil {
-			errMsg = string(resBody)
+			errMsg = truncate(resBody, maxErrorMessage)
 		}
-		err = errorFromStatusCode(res.StatusCode, errMsg)
+		err = errorFromStatusCode(method, path, res.StatusCode, errMsg)
 		return nil, err
 	}
 	return res, nil
 }
 
+// discardBody drains and closes the body of a response that will not be
+// used, so that its connection can be reused.
+func discardBody(res *http.Response) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 4096))
+	res.Body.Close()
+}
+
 // String returns a pointer value for the string value passed in.
 func String(v string) *string {
 	return &v
@@ -0,0 +1,177 @@
+package petstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDoRequestAborted(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		// abort returns a context that is aborted once the store received
+		// the request, and the error the request should fail with.
+		abort func(received <-chan struct{}) (context.Context, error)
+	}{
+		"Cancelled": {
+			reason: "Cancelling the context should abort a request in flight.",
+			abort: func(received <-chan struct{}) (context.Context, error) {
+				ctx, cancel := context.WithCancel(context.Background())
+				go func() {
+					<-received
+					cancel()
+				}()
+				return ctx, context.Canceled
+			},
+		},
+		"DeadlineExceeded": {
+			reason: "A request in flight should be aborted when the deadline of its context passes.",
+			abort: func(_ <-chan struct{}) (context.Context, error) {
+				ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+				t.Cleanup(cancel)
+				return ctx, context.DeadlineExceeded
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			received := make(chan struct{})
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				close(received)
+				// The store never answers; only the client can end the
+				// request.
+				select {
+				case <-r.Context().Done():
+				case <-time.After(10 * time.Second):
+				}
+			}))
+			defer srv.Close()
+
+			ctx, want := tc.abort(received)
+			start := time.Now()
+			_, err := New(GetConfig(srv.URL)).DoRequest(ctx, "/pet/1", http.MethodGet, nil)
+			if !errors.Is(err, want) {
+				t.Errorf("\n%s\nc.DoRequest(...): want error %v, got %v", tc.reason, want, err)
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("\n%s\nc.DoRequest(...): returned after %s, not when its context was aborted", tc.reason, elapsed)
+			}
+		})
+	}
+}
+
+func TestErrorBody(t *testing.T) {
+	// An endless body of a failed response.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		chunk := []byte(strings.Repeat("x", 4096))
+		for r.Context().Err() == nil {
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+		}
+	}))
+	defer srv.Close()
+
+	cases := map[string]struct {
+		reason string
+		send   func(ctx context.Context) error
+	}{
+		"Request": {
+			reason: "The body of a failed request should be read up to the maximum response size, and truncated in its error.",
+			send: func(ctx context.Context) error {
+				c := New(GetConfig(srv.URL, WithMaxResponseSize(64<<10), WithRetryPolicy(RetryPolicy{MaxRetries: -1})))
+				_, err := c.DoRequest(ctx, "/pet/1", http.MethodGet, nil)
+				return err
+			},
+		},
+		"Token": {
+			reason: "The body of a failed token request should be read up to the maximum response size, and truncated in its error.",
+			send: func(ctx context.Context) error {
+				_, err := NewTokenSource(OAuth2Options{TokenURL: srv.URL, ClientID: "client"}, srv.Client()).Token(ctx)
+				return err
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			err := tc.send(ctx)
+			if !IsErrorServer(err) {
+				t.Fatalf("\n%s\nwant a ServerException, got %v", tc.reason, err)
+			}
+			var apiErr *ServerException
+			errors.As(err, &apiErr)
+			want := strings.Repeat("x", maxErrorMessage) + "...(truncated)"
+			if diff := cmp.Diff(want, apiErr.ErrorMessage()); diff != "" {
+				t.Errorf("\n%s\nErrorMessage(): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestStreamRequest(t *testing.T) {
+	const timeout = 100 * time.Millisecond
+
+	cases := map[string]struct {
+		reason string
+		// headerDelay and bodyDelay delay the headers and the end of the
+		// body of the response.
+		headerDelay time.Duration
+		bodyDelay   time.Duration
+		wantErr     error
+		wantBody    string
+	}{
+		"SlowBody": {
+			reason:    "A body streamed for longer than the request timeout should be read whole.",
+			bodyDelay: 3 * timeout,
+			wantBody:  `[{"id":1},{"id":2}]`,
+		},
+		"SlowHeaders": {
+			reason:      "Headers not received within the request timeout should be an error.",
+			headerDelay: 3 * timeout,
+			wantErr:     context.DeadlineExceeded,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(tc.headerDelay)
+				_, _ = io.WriteString(w, `[{"id":1},`)
+				w.(http.Flusher).Flush()
+				time.Sleep(tc.bodyDelay)
+				_, _ = io.WriteString(w, `{"id":2}]`)
+			}))
+			defer srv.Close()
+
+			hc, _ := NewHTTPClient(TransportOptions{RequestTimeout: timeout})
+			c := New(GetConfig(srv.URL, WithHTTPClient(hc), WithRetryPolicy(RetryPolicy{MaxRetries: -1})))
+			res, err := c.StreamRequest(context.Background(), "/pet/findByStatus", nil)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("\n%s\nc.StreamRequest(...): want error %v, got %v", tc.reason, tc.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			defer res.Body.Close()
+			body, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Fatalf("\n%s\nreading the body: %v", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.wantBody, string(body)); diff != "" {
+				t.Errorf("\n%s\nbody: -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package petstore
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ProxyOptions routes requests to the pet store through an HTTP proxy. When
+// no URL is set the proxy is taken from the HTTP_PROXY, HTTPS_PROXY and
+// NO_PROXY environment variables.
+type ProxyOptions struct {
+	URL string
+	// NoProxy lists the hosts that are reached directly. An entry may be a
+	// host name, which also matches its subdomains, a domain prefixed with a
+	// dot, an IP address, a CIDR range or "*". Host names and IP addresses
+	// may be followed by a port.
+	NoProxy []string
+}
+
+// A ProxyError is returned when the proxy configuration is invalid.
+type ProxyError struct {
+	Err error
+}
+
+func (e *ProxyError) Error() string { return "ProxyError: " + e.Err.Error() }
+func (e *ProxyError) Unwrap() error { return e.Err }
+
+func IsErrorProxy(err error) bool {
+	var proxyError *ProxyError
+	return errors.As(err, &proxyError)
+}
+
+// proxyFunc returns the Proxy function of the transport described by the
+// options.
+func (o ProxyOptions) proxyFunc() (func(*http.Request) (*url.URL, error), error) {
+	if o.URL == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+	u, err := url.Parse(o.URL)
+	if err != nil {
+		return nil, &ProxyError{Err: err}
+	}
+	switch {
+	case u.Host == "":
+		return nil, &ProxyError{Err: fmt.Errorf("proxy URL %q has no host", o.URL)}
+	case u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5":
+		return nil, &ProxyError{Err: fmt.Errorf("proxy URL %q has unsupported scheme %q", o.URL, u.Scheme)}
+	}
+	return func(req *http.Request) (*url.URL, error) {
+		if bypassProxy(req.URL, o.NoProxy) {
+			return nil, nil
+		}
+		return u, nil
+	}, nil
+}
+
+// bypassProxy reports whether the supplied URL matches one of the noProxy
+// entries.
+func bypassProxy(u *url.URL, noProxy []string) bool {
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	ip := net.ParseIP(host)
+
+	for _, entry := range noProxy {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case entry == "":
+			continue
+		case entry == "*":
+			return true
+		}
+		if _, cidr, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && cidr.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		h, p := entry, ""
+		if eh, ep, err := net.SplitHostPort(entry); err == nil {
+			h, p = eh, ep
+		}
+		if p != "" && p != port {
+			continue
+		}
+		if matchHost(host, strings.Trim(h, "[]")) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchHost(host, entry string) bool {
+	if strings.HasPrefix(entry, ".") {
+		return strings.HasSuffix(host, entry)
+	}
+	return host == entry || strings.HasSuffix(host, "."+entry)
+}
@@ -0,0 +1,123 @@
+package petstore
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBypassProxy(t *testing.T) {
+	cases := map[string]struct {
+		reason  string
+		url     string
+		noProxy []string
+		want    bool
+	}{
+		"NoEntries": {
+			reason: "A request should go through the proxy when no host is exempted.",
+			url:    "https://petstore.example.com/v2",
+		},
+		"Wildcard": {
+			reason:  "A wildcard should exempt every host.",
+			url:     "https://petstore.example.com/v2",
+			noProxy: []string{"*"},
+			want:    true,
+		},
+		"ExactHost": {
+			reason:  "A host name should exempt that host.",
+			url:     "https://petstore.example.com/v2",
+			noProxy: []string{"petstore.example.com"},
+			want:    true,
+		},
+		"Subdomain": {
+			reason:  "A host name should exempt its subdomains.",
+			url:     "https://petstore.example.com/v2",
+			noProxy: []string{"example.com"},
+			want:    true,
+		},
+		"DotDomain": {
+			reason:  "A domain prefixed with a dot should not exempt the domain itself.",
+			url:     "https://example.com/v2",
+			noProxy: []string{".example.com"},
+		},
+		"PartialLabel": {
+			reason:  "A host name should not match hosts merely ending with the same characters.",
+			url:     "https://notexample.com/v2",
+			noProxy: []string{"example.com"},
+		},
+		"MatchingPort": {
+			reason:  "A host with a port should exempt requests to that port.",
+			url:     "https://petstore.example.com/v2",
+			noProxy: []string{"petstore.example.com:443"},
+			want:    true,
+		},
+		"OtherPort": {
+			reason:  "A host with a port should not exempt requests to other ports.",
+			url:     "http://petstore.example.com:8080/v2",
+			noProxy: []string{"petstore.example.com:443"},
+		},
+		"CIDR": {
+			reason:  "A CIDR range should exempt the IP addresses it contains.",
+			url:     "http://10.0.3.7/v2",
+			noProxy: []string{"10.0.0.0/16"},
+			want:    true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			u, _ := url.Parse(tc.url)
+			got := bypassProxy(u, tc.noProxy)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nbypassProxy(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestProxyAndHeaders(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A forward proxy receives the absolute URL of the pet store.
+		proxied = append(proxied, r.URL.String())
+		if got := r.Header.Get("X-Store-Tenant"); got != "acme" {
+			t.Errorf("X-Store-Tenant: want %q, got %q", "acme", got)
+		}
+		if got := r.Header.Get(apiKeyHeader); got != "key" {
+			t.Errorf("%s: want %q, got %q", apiKeyHeader, "key", got)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer proxy.Close()
+
+	hc, err := NewHTTPClient(TransportOptions{Proxy: ProxyOptions{URL: proxy.URL}})
+	if err != nil {
+		t.Fatalf("NewHTTPClient(...): %v", err)
+	}
+	c := New(GetConfig("http://petstore.invalid/v2",
+		WithHTTPClient(hc),
+		WithAPIKey("key"),
+		// The API key set by the client takes precedence over static headers.
+		WithHeaders(map[string]string{"X-Store-Tenant": "acme", apiKeyHeader: "other"}),
+	))
+	res, err := c.DoRequest(context.Background(), "/pet/1", http.MethodGet, nil)
+	if err != nil {
+		t.Fatalf("c.DoRequest(...): %v", err)
+	}
+	res.Body.Close()
+
+	if diff := cmp.Diff([]string{"http://petstore.invalid/v2/pet/1"}, proxied); diff != "" {
+		t.Errorf("proxied requests: -want, +got:\n%s\n", diff)
+	}
+}
+
+func TestInvalidProxy(t *testing.T) {
+	_, err := NewHTTPClient(TransportOptions{Proxy: ProxyOptions{URL: "ftp://proxy.example.com"}})
+	if !IsErrorProxy(err) {
+		t.Errorf("NewHTTPClient(...): want ProxyError, got %v", err)
+	}
+}
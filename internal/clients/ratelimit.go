@@ -0,0 +1,123 @@
+package petstore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// RateLimitOptions bound the requests sent to a pet store. Zero values mean
+// no limit.
+type RateLimitOptions struct {
+	// RequestsPerSecond is the sustained rate of requests.
+	RequestsPerSecond float64
+	// Burst is the number of requests that may be sent at once above the
+	// sustained rate. Defaults to RequestsPerSecond, rounded up.
+	Burst int
+	// MaxInFlight is the maximum number of requests awaiting a response, or
+	// whose response is still being read.
+	MaxInFlight int
+}
+
+// A Limiter bounds the rate and concurrency of the requests sent to a pet
+// store. It is meant to be shared by all the clients of a ProviderConfig.
+type Limiter struct {
+	rate     *rate.Limiter
+	inFlight chan struct{}
+}
+
+// NewLimiter returns a Limiter enforcing the supplied options.
+func NewLimiter(o RateLimitOptions) *Limiter {
+	l := &Limiter{}
+	if o.RequestsPerSecond > 0 {
+		burst := o.Burst
+		if burst <= 0 {
+			burst = int(math.Ceil(o.RequestsPerSecond))
+		}
+		l.rate = rate.NewLimiter(rate.Limit(o.RequestsPerSecond), burst)
+	}
+	if o.MaxInFlight > 0 {
+		l.inFlight = make(chan struct{}, o.MaxInFlight)
+	}
+	return l
+}
+
+// wait blocks until a request may be sent, or until ctx is done, and returns
+// how long it blocked. The returned function must be called once the body of
+// the response to the request was closed.
+func (l *Limiter) wait(ctx context.Context) (release func(), waited time.Duration, err error) {
+	release = func() {}
+	if l == nil {
+		return release, 0, nil
+	}
+	start := time.Now()
+	if l.rate != nil {
+		if err := l.rate.Wait(ctx); err != nil {
+			return nil, time.Since(start), fmt.Errorf("cannot wait for rate limit: %w", err)
+		}
+	}
+	if l.inFlight != nil {
+		select {
+		case l.inFlight <- struct{}{}:
+			release = func() { <-l.inFlight }
+		case <-ctx.Done():
+			return nil, time.Since(start), fmt.Errorf("cannot wait for a request slot: %w", ctx.Err())
+		}
+	}
+	return release, time.Since(start), nil
+}
+
+type cachedLimiter struct {
+	options RateLimitOptions
+	limiter *Limiter
+}
+
+// A LimiterCache hands out one Limiter per ProviderConfig, so that its limits
+// apply to all the resources using it. A Limiter is replaced when the limits
+// of its ProviderConfig change.
+type LimiterCache struct {
+	mu       sync.Mutex
+	limiters map[string]cachedLimiter
+}
+
+// NewLimiterCache returns an empty LimiterCache.
+func NewLimiterCache() *LimiterCache {
+	return &LimiterCache{
+		limiters: map[string]cachedLimiter{},
+	}
+}
+
+// Get returns the Limiter cached for the named ProviderConfig, building a new
+// one if none is cached yet or if its options changed.
+func (c *LimiterCache) Get(name string, o RateLimitOptions) *Limiter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached, ok := c.limiters[name]
+	if ok && cached.options == o {
+		return cached.limiter
+	}
+	l := NewLimiter(o)
+	c.limiters[name] = cachedLimiter{options: o, limiter: l}
+	return l
+}
+
+// A releasingBody is the body of a response that holds a request slot of a
+// Limiter until it is closed, so that a response streamed for a long time
+// still counts as in flight.
+type releasingBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
@@ -0,0 +1,119 @@
+package petstore
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLimiterMaxInFlight(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		inFlight int
+		peak     int
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > peak {
+			peak = inFlight
+		}
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	l := NewLimiter(RateLimitOptions{MaxInFlight: 2})
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Every request gets its own client, as every reconcile does.
+			c := New(GetConfig(srv.URL, WithLimiter(l)))
+			res, err := c.DoRequest(context.Background(), "/pet/1", http.MethodGet, nil)
+			if err != nil {
+				t.Errorf("c.DoRequest(...): %v", err)
+				return
+			}
+			res.Body.Close()
+		}()
+	}
+	wg.Wait()
+
+	if diff := cmp.Diff(2, peak); diff != "" {
+		t.Errorf("peak requests in flight: -want, +got:\n%s\n", diff)
+	}
+}
+
+func TestLimiterHoldsSlotUntilBodyClosed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	l := NewLimiter(RateLimitOptions{MaxInFlight: 1})
+	send := func(timeout time.Duration) (*http.Response, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		return New(GetConfig(srv.URL, WithLimiter(l))).DoRequest(ctx, "/pet/1", http.MethodGet, nil)
+	}
+
+	res, err := send(time.Second)
+	if err != nil {
+		t.Fatalf("c.DoRequest(...): %v", err)
+	}
+	if _, err := send(50 * time.Millisecond); err == nil {
+		t.Error("c.DoRequest(...): a request should wait for the slot held by a response whose body is not closed")
+	}
+	res.Body.Close()
+	res, err = send(time.Second)
+	if err != nil {
+		t.Fatalf("c.DoRequest(...): the slot should be released once the body is closed: %v", err)
+	}
+	res.Body.Close()
+}
+
+func TestLimiterRespectsContext(t *testing.T) {
+	var sent int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent++
+	}))
+	defer srv.Close()
+
+	var observed []Request
+	c := New(GetConfig(srv.URL,
+		WithLimiter(NewLimiter(RateLimitOptions{RequestsPerSecond: 0.1, Burst: 1})),
+		WithRequestObserver(func(_ context.Context, r Request) { observed = append(observed, r) }),
+	))
+
+	res, err := c.DoRequest(context.Background(), "/pet/1", http.MethodGet, nil)
+	if err != nil {
+		t.Fatalf("first c.DoRequest(...): %v", err)
+	}
+	res.Body.Close()
+
+	// The next token is only available in 10s, which is beyond the deadline.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = c.DoRequest(ctx, "/pet/1", http.MethodGet, nil)
+	if err == nil {
+		t.Fatal("second c.DoRequest(...): want error, got nil")
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("second c.DoRequest(...): waited %s despite the deadline", time.Since(start))
+	}
+	if diff := cmp.Diff(1, sent); diff != "" {
+		t.Errorf("requests sent: -want, +got:\n%s\n", diff)
+	}
+	if diff := cmp.Diff(1, len(observed)); diff != "" {
+		t.Errorf("requests observed: -want, +got:\n%s\n", diff)
+	}
+}
@@ -0,0 +1,265 @@
+// Package replay records the requests sent to a pet store and the responses
+// it returns to a fixture file, and replays them offline. It lets clients be
+// tested against the exact traffic of a real store without reaching it.
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+const redacted = "REDACTED"
+
+// sensitiveHeaders are never written to a fixture in the clear.
+var sensitiveHeaders = map[string]bool{
+	"Authorization":                    true,
+	"Proxy-Authorization":              true,
+	"Cookie":                           true,
+	"Set-Cookie":                       true,
+	http.CanonicalHeaderKey("api_key"): true,
+}
+
+// volatileHeaders differ with every request, such as those propagating the
+// trace of a reconcile. They are neither recorded nor matched.
+var volatileHeaders = map[string]bool{
+	"Traceparent": true,
+	"Tracestate":  true,
+}
+
+// An Interaction is a request and the response the pet store returned.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// A Request as sent to the pet store.
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+	// BinaryBody holds a body that is not valid UTF-8, such as an image,
+	// instead of Body. It is recorded base64 encoded.
+	BinaryBody []byte `json:"binaryBody,omitempty"`
+}
+
+// A Response as returned by the pet store.
+type Response struct {
+	StatusCode int         `json:"status"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+	BinaryBody []byte      `json:"binaryBody,omitempty"`
+}
+
+// A Transport is an http.RoundTripper that either records interactions with
+// a pet store, or replays recorded interactions without contacting it. Secret
+// values and sensitive headers are replaced by REDACTED before they are
+// recorded, and before a request is matched against a recorded one. Trace
+// context headers are dropped.
+type Transport struct {
+	path    string
+	next    http.RoundTripper
+	secrets []string
+
+	mu           sync.Mutex
+	interactions []Interaction
+	replayed     int
+}
+
+// NewRecorder returns a Transport that sends requests through the supplied
+// RoundTripper, or http.DefaultTransport if it is nil, and records them. The
+// interactions are written to the fixture at path by Save.
+func NewRecorder(path string, next http.RoundTripper, secrets ...string) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Transport{path: path, next: next, secrets: secrets}
+}
+
+// NewReplayer returns a Transport that answers requests with the responses
+// recorded in the fixture at path. Requests must be sent in the order they
+// were recorded, and match their recording exactly once scrubbed of the
+// supplied secrets.
+func NewReplayer(path string, secrets ...string) (*Transport, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read fixture: %w", err)
+	}
+	t := &Transport{path: path, secrets: secrets}
+	if err := json.Unmarshal(data, &t.interactions); err != nil {
+		return nil, fmt.Errorf("cannot parse fixture %s: %w", path, err)
+	}
+	return t, nil
+}
+
+// RoundTrip records or replays a single interaction.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(&req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read request body: %w", err)
+	}
+	r := Request{
+		Method: req.Method,
+		URL:    t.scrub(req.URL.String()),
+		Header: t.scrubHeader(req.Header),
+	}
+	r.Body, r.BinaryBody = t.scrubBody(body)
+	if t.next == nil {
+		return t.replay(req, r)
+	}
+	return t.record(req, r)
+}
+
+func (t *Transport) record(req *http.Request, r Request) (*http.Response, error) {
+	res, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := readBody(&res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read response body: %w", err)
+	}
+
+	rr := Response{
+		StatusCode: res.StatusCode,
+		Header:     t.scrubHeader(res.Header),
+	}
+	rr.Body, rr.BinaryBody = t.scrubBody(body)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.interactions = append(t.interactions, Interaction{Request: r, Response: rr})
+	return res, nil
+}
+
+func (t *Transport) replay(req *http.Request, r Request) (*http.Response, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.replayed >= len(t.interactions) {
+		return nil, fmt.Errorf("%s %s: no interaction left in fixture %s", r.Method, r.URL, t.path)
+	}
+	i := t.interactions[t.replayed]
+	if !reflect.DeepEqual(i.Request, r) {
+		return nil, fmt.Errorf("interaction %d of fixture %s does not match:\nrecorded: %s\n    sent: %s", t.replayed, t.path, format(i.Request), format(r))
+	}
+	t.replayed++
+	body := []byte(i.Response.Body)
+	if i.Response.BinaryBody != nil {
+		body = i.Response.BinaryBody
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", i.Response.StatusCode, http.StatusText(i.Response.StatusCode)),
+		StatusCode:    i.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        i.Response.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// Save writes the recorded interactions to the fixture. It does nothing when
+// replaying.
+func (t *Transport) Save() error {
+	if t.next == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	data, err := json.MarshalIndent(t.interactions, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(t.path), 0o750); err != nil {
+		return fmt.Errorf("cannot create fixture directory: %w", err)
+	}
+	return os.WriteFile(t.path, append(data, '\n'), 0o600)
+}
+
+// Done returns an error if some of the recorded interactions were not
+// replayed.
+func (t *Transport) Done() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.next != nil || t.replayed == len(t.interactions) {
+		return nil
+	}
+	return fmt.Errorf("%d of %d interactions of fixture %s were not replayed", len(t.interactions)-t.replayed, len(t.interactions), t.path)
+}
+
+func (t *Transport) scrub(s string) string {
+	for _, v := range t.secrets {
+		if v != "" {
+			s = strings.ReplaceAll(s, v, redacted)
+		}
+	}
+	return s
+}
+
+// scrubBody scrubs the supplied body, and returns it either as a string or,
+// when it is not valid UTF-8, as binary data.
+func (t *Transport) scrubBody(body []byte) (string, []byte) {
+	if utf8.Valid(body) {
+		return t.scrub(string(body)), nil
+	}
+	for _, v := range t.secrets {
+		if v != "" {
+			body = bytes.ReplaceAll(body, []byte(v), []byte(redacted))
+		}
+	}
+	return "", body
+}
+
+func (t *Transport) scrubHeader(h http.Header) http.Header {
+	if len(h) == 0 {
+		return nil
+	}
+	out := make(http.Header, len(h))
+	for k, vs := range h {
+		if volatileHeaders[http.CanonicalHeaderKey(k)] {
+			continue
+		}
+		out[k] = make([]string, len(vs))
+		for i, v := range vs {
+			if sensitiveHeaders[http.CanonicalHeaderKey(k)] {
+				v = redacted
+			}
+			out[k][i] = t.scrub(v)
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// readBody reads the supplied body and replaces it so that it can be read
+// again.
+func readBody(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil || *body == http.NoBody {
+		return nil, nil
+	}
+	defer (*body).Close()
+	data, err := io.ReadAll(*body)
+	if err != nil {
+		return nil, err
+	}
+	*body = io.NopCloser(bytes.NewReader(data))
+	return data, nil
+}
+
+func format(r Request) string {
+	data, _ := json.Marshal(r)
+	return string(data)
+}
@@ -0,0 +1,176 @@
+package replay
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRecordReplay(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "session=abc")
+		_, _ = io.WriteString(w, "token s3cr3t issued")
+	}))
+	defer srv.Close()
+
+	fixture := filepath.Join(t.TempDir(), "fixture.json")
+	send := func(rt http.RoundTripper, body string) (string, error) {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+"/pet", strings.NewReader(body))
+		req.Header.Set("api_key", "s3cr3t")
+		req.Header.Set("X-Tenant", "s3cr3t-tenant")
+		res, err := (&http.Client{Transport: rt}).Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer res.Body.Close()
+		b, _ := io.ReadAll(res.Body)
+		return string(b), nil
+	}
+
+	rec := NewRecorder(fixture, nil, "s3cr3t")
+	got, err := send(rec, `{"key":"s3cr3t"}`)
+	if err != nil {
+		t.Fatalf("recording: %v", err)
+	}
+	if diff := cmp.Diff("token s3cr3t issued", got); diff != "" {
+		t.Errorf("recorded response: -want, +got:\n%s\n", diff)
+	}
+	if err := rec.Save(); err != nil {
+		t.Fatalf("rec.Save(): %v", err)
+	}
+	data, _ := os.ReadFile(fixture)
+	if strings.Contains(string(data), "s3cr3t") || strings.Contains(string(data), "abc") {
+		t.Errorf("fixture holds a secret:\n%s", data)
+	}
+
+	cases := map[string]struct {
+		reason  string
+		body    string
+		want    string
+		wantErr bool
+	}{
+		"Match": {
+			reason: "A request matching its recording should be answered with the scrubbed recorded response.",
+			body:   `{"key":"s3cr3t"}`,
+			want:   "token REDACTED issued",
+		},
+		"Mismatch": {
+			reason:  "A request that differs from its recording should fail.",
+			body:    `{"key":"other"}`,
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			rp, err := NewReplayer(fixture, "s3cr3t")
+			if err != nil {
+				t.Fatalf("NewReplayer(...): %v", err)
+			}
+			got, err := send(rp, tc.body)
+			if diff := cmp.Diff(tc.wantErr, err != nil); diff != "" {
+				t.Errorf("\n%s\nerror: -want, +got:\n%s\nerror: %v", tc.reason, diff, err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nresponse: -want, +got:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.wantErr, rp.Done() != nil); diff != "" {
+				t.Errorf("\n%s\nrp.Done(): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestRecordReplayBinary(t *testing.T) {
+	image := []byte{0x89, 'P', 'N', 'G', 0xff, 0x00, 0xfe}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(b)
+	}))
+	defer srv.Close()
+
+	fixture := filepath.Join(t.TempDir(), "fixture.json")
+	send := func(rt http.RoundTripper, body []byte) ([]byte, error) {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+"/pet/1/uploadImage", bytes.NewReader(body))
+		res, err := (&http.Client{Transport: rt}).Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer res.Body.Close()
+		return io.ReadAll(res.Body)
+	}
+
+	rec := NewRecorder(fixture, nil)
+	if _, err := send(rec, image); err != nil {
+		t.Fatalf("recording: %v", err)
+	}
+	if err := rec.Save(); err != nil {
+		t.Fatalf("rec.Save(): %v", err)
+	}
+
+	rp, err := NewReplayer(fixture)
+	if err != nil {
+		t.Fatalf("NewReplayer(...): %v", err)
+	}
+	got, err := send(rp, image)
+	if err != nil {
+		t.Fatalf("replaying: %v", err)
+	}
+	if diff := cmp.Diff(image, got); diff != "" {
+		t.Errorf("replayed response: -want, +got:\n%s\n", diff)
+	}
+
+	rp, _ = NewReplayer(fixture)
+	if _, err := send(rp, append([]byte{0x00}, image...)); err == nil {
+		t.Error("replaying a different binary body: want error, got nil")
+	}
+}
+
+func TestReplayIgnoresTraceContext(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	fixture := filepath.Join(t.TempDir(), "fixture.json")
+	send := func(rt http.RoundTripper, traceparent string) error {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/pet/1", nil)
+		req.Header.Set("traceparent", traceparent)
+		req.Header.Set("tracestate", "vendor="+traceparent)
+		res, err := (&http.Client{Transport: rt}).Do(req)
+		if err != nil {
+			return err
+		}
+		return res.Body.Close()
+	}
+
+	rec := NewRecorder(fixture, nil)
+	if err := send(rec, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"); err != nil {
+		t.Fatalf("recording: %v", err)
+	}
+	if err := rec.Save(); err != nil {
+		t.Fatalf("rec.Save(): %v", err)
+	}
+	data, _ := os.ReadFile(fixture)
+	if strings.Contains(strings.ToLower(string(data)), "trace") {
+		t.Errorf("fixture holds trace context:\n%s", data)
+	}
+
+	rp, err := NewReplayer(fixture)
+	if err != nil {
+		t.Fatalf("NewReplayer(...): %v", err)
+	}
+	if err := send(rp, "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"); err != nil {
+		t.Errorf("replaying with another trace context: %v", err)
+	}
+	if err := rp.Done(); err != nil {
+		t.Errorf("rp.Done(): %v", err)
+	}
+}
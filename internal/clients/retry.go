@@ -0,0 +1,148 @@
+package petstore
+
+import (
+	"context"
+	"math/rand"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultMaxRetries     = 3
+	defaultInitialBackoff = 500 * time.Millisecond
+	defaultMaxBackoff     = 30 * time.Second
+)
+
+// A RetryPolicy controls how failed requests to the pet store are retried.
+// Zero values are replaced by sensible defaults; use a negative MaxRetries to
+// disable retries altogether. A request is not retried when the backoff would
+// outlast the deadline of its context; its last error is returned instead.
+type RetryPolicy struct {
+	MaxRetries     int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxRetries == 0 {
+		p.MaxRetries = defaultMaxRetries
+	}
+	if p.MaxRetries < 0 {
+		p.MaxRetries = 0
+	}
+	if p.InitialBackoff == 0 {
+		p.InitialBackoff = defaultInitialBackoff
+	}
+	if p.MaxBackoff == 0 {
+		p.MaxBackoff = defaultMaxBackoff
+	}
+	return p
+}
+
+// A RetryAttempt describes a request that failed and is about to be retried.
+type RetryAttempt struct {
+	Method string
+	Path   string
+	// Attempt is the number of the retry about to be sent, starting at 1.
+	Attempt int
+	// StatusCode of the failed response, or zero if no response was received.
+	StatusCode int
+	// Err is the network error that caused the retry, if any.
+	Err error
+	// Wait is how long the client waits before retrying.
+	Wait time.Duration
+}
+
+// A RetryNotifier is called before each retry of a request.
+type RetryNotifier func(ctx context.Context, a RetryAttempt)
+
+// backoff returns how long to wait before the supplied retry attempt, using
+// capped exponential backoff with full jitter.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.MaxBackoff
+	if attempt < 32 {
+		if exp := p.InitialBackoff << uint(attempt); exp > 0 && exp < d {
+			d = exp
+		}
+	}
+	return time.Duration(rand.Int63n(int64(d) + 1)) //nolint:gosec // Jitter does not need a secure source.
+}
+
+// IdempotencyKeyHeader carries a key identifying a request that the pet store
+// must not process more than once, however many times it is sent.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// retryable reports whether a request that produced the supplied response or
+// error may be sent again.
+func retryable(method string, header http.Header, res *http.Response, err error) bool {
+	if err != nil {
+		// Errors carrying a status code come from a response, for example
+		// of the token endpoint, rather than from the network. TLS errors
+		// will not go away by themselves either.
+		if _, ok := StatusCode(err); ok || IsErrorTLS(err) {
+			return false
+		}
+		return isIdempotent(method, header)
+	}
+	switch res.StatusCode {
+	case http.StatusTooManyRequests:
+		// A throttled request was not processed, so it is safe to resend
+		// whatever its method.
+		return true
+	case http.StatusRequestTimeout, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return isIdempotent(method, header)
+	}
+	return false
+}
+
+// isIdempotent reports whether a request may be sent more than once, either
+// because of its method or because it carries an idempotency key. A PATCH is
+// idempotent when it is a JSON merge patch, which sets fields to values.
+func isIdempotent(method string, header http.Header) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	case http.MethodPatch:
+		if mt, _, err := mime.ParseMediaType(header.Get("Content-Type")); err == nil && mt == MediaTypeMergePatch {
+			return true
+		}
+	}
+	return header.Get(IdempotencyKeyHeader) != ""
+}
+
+// retryAfter parses the Retry-After header of the supplied response, which
+// may hold either a number of seconds or an HTTP date.
+func retryAfter(res *http.Response, now time.Time) (time.Duration, bool) {
+	if res == nil {
+		return 0, false
+	}
+	v := res.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if s, err := strconv.Atoi(v); err == nil && s >= 0 {
+		return time.Duration(s) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := t.Sub(now)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// sleep waits for the supplied duration or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
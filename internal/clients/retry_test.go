@@ -0,0 +1,252 @@
+package petstore
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDoRequestRetry(t *testing.T) {
+	type args struct {
+		method    string
+		reqHeader http.Header
+		statuses  []int
+		header    http.Header
+		policy    RetryPolicy
+	}
+
+	type want struct {
+		calls   int
+		retries []int
+		err     bool
+	}
+
+	fast := RetryPolicy{InitialBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"TransientThenSuccess": {
+			reason: "An idempotent request should be retried until it succeeds.",
+			args: args{
+				method:   http.MethodGet,
+				statuses: []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusOK},
+				policy:   fast,
+			},
+			want: want{
+				calls:   3,
+				retries: []int{http.StatusBadGateway, http.StatusServiceUnavailable},
+			},
+		},
+		"RetriesExhausted": {
+			reason: "A request should not be retried more than MaxRetries times.",
+			args: args{
+				method:   http.MethodDelete,
+				statuses: []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway},
+				policy:   RetryPolicy{MaxRetries: 2, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
+			},
+			want: want{
+				calls:   3,
+				retries: []int{http.StatusBadGateway, http.StatusBadGateway},
+				err:     true,
+			},
+		},
+		"NonIdempotentNotRetried": {
+			reason: "A POST should not be retried on a server error.",
+			args: args{
+				method:   http.MethodPost,
+				statuses: []int{http.StatusBadGateway, http.StatusOK},
+				policy:   fast,
+			},
+			want: want{
+				calls: 1,
+				err:   true,
+			},
+		},
+		"IdempotencyKeyPostRetried": {
+			reason: "A POST carrying an idempotency key should be retried on a server error.",
+			args: args{
+				method:    http.MethodPost,
+				reqHeader: http.Header{IdempotencyKeyHeader: []string{"pet-uid-1"}},
+				statuses:  []int{http.StatusBadGateway, http.StatusOK},
+				policy:    fast,
+			},
+			want: want{
+				calls:   2,
+				retries: []int{http.StatusBadGateway},
+			},
+		},
+		"MergePatchRetried": {
+			reason: "A JSON merge patch is idempotent and should be retried on a server error.",
+			args: args{
+				method:    http.MethodPatch,
+				reqHeader: http.Header{"Content-Type": []string{MediaTypeMergePatch}},
+				statuses:  []int{http.StatusBadGateway, http.StatusOK},
+				policy:    fast,
+			},
+			want: want{
+				calls:   2,
+				retries: []int{http.StatusBadGateway},
+			},
+		},
+		"OtherPatchNotRetried": {
+			reason: "A PATCH that is not a JSON merge patch should not be retried on a server error.",
+			args: args{
+				method:    http.MethodPatch,
+				reqHeader: http.Header{"Content-Type": []string{"application/json-patch+json"}},
+				statuses:  []int{http.StatusBadGateway, http.StatusOK},
+				policy:    fast,
+			},
+			want: want{
+				calls: 1,
+				err:   true,
+			},
+		},
+		"ThrottledPostRetried": {
+			reason: "A throttled POST was not processed and should be retried.",
+			args: args{
+				method:   http.MethodPost,
+				statuses: []int{http.StatusTooManyRequests, http.StatusOK},
+				header:   http.Header{"Retry-After": []string{"0"}},
+				policy:   fast,
+			},
+			want: want{
+				calls:   2,
+				retries: []int{http.StatusTooManyRequests},
+			},
+		},
+		"RetryAfterTooLong": {
+			reason: "A request should not be retried if the store asks to wait longer than MaxBackoff.",
+			args: args{
+				method:   http.MethodGet,
+				statuses: []int{http.StatusTooManyRequests, http.StatusOK},
+				header:   http.Header{"Retry-After": []string{"120"}},
+				policy:   fast,
+			},
+			want: want{
+				calls: 1,
+				err:   true,
+			},
+		},
+		"ClientErrorNotRetried": {
+			reason: "A request rejected by the store should not be retried.",
+			args: args{
+				method:   http.MethodPut,
+				statuses: []int{http.StatusBadRequest, http.StatusOK},
+				policy:   fast,
+			},
+			want: want{
+				calls: 1,
+				err:   true,
+			},
+		},
+		"RetriesDisabled": {
+			reason: "A negative MaxRetries should disable retries.",
+			args: args{
+				method:   http.MethodGet,
+				statuses: []int{http.StatusServiceUnavailable, http.StatusOK},
+				policy:   RetryPolicy{MaxRetries: -1},
+			},
+			want: want{
+				calls: 1,
+				err:   true,
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			calls := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				status := tc.args.statuses[calls]
+				calls++
+				for k, v := range tc.args.header {
+					w.Header()[k] = v
+				}
+				w.WriteHeader(status)
+			}))
+			defer srv.Close()
+
+			var retries []int
+			c := New(GetConfig(srv.URL,
+				WithRetryPolicy(tc.args.policy),
+				WithRetryNotifier(func(_ context.Context, a RetryAttempt) {
+					retries = append(retries, a.StatusCode)
+				})))
+
+			res, err := c.DoRequestWithHeader(context.Background(), "/pet/1", tc.args.method, nil, tc.args.reqHeader)
+			if res != nil {
+				res.Body.Close()
+			}
+			if diff := cmp.Diff(tc.want.err, err != nil); diff != "" {
+				t.Errorf("\n%s\nc.DoRequest(...): -want error, +got error:\n%s\nerror: %v", tc.reason, diff, err)
+			}
+			if diff := cmp.Diff(tc.want.calls, calls); diff != "" {
+				t.Errorf("\n%s\nc.DoRequest(...): -want calls, +got calls:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.retries, retries); diff != "" {
+				t.Errorf("\n%s\nc.DoRequest(...): -want retries, +got retries:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestDoRequestRetryContextCancelled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := New(GetConfig(srv.URL,
+		WithRetryPolicy(RetryPolicy{InitialBackoff: time.Hour, MaxBackoff: time.Hour}),
+		WithRetryNotifier(func(_ context.Context, _ RetryAttempt) { cancel() })))
+
+	done := make(chan error)
+	go func() {
+		_, err := c.DoRequest(ctx, "/pet/1", http.MethodGet, nil)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if diff := cmp.Diff(context.Canceled, err, cmp.Comparer(func(a, b error) bool { return a == b })); diff != "" {
+			t.Errorf("c.DoRequest(...): -want error, +got error:\n%s\n", diff)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("c.DoRequest(...): did not return after its context was cancelled")
+	}
+}
+
+func TestDoRequestRetryDeadline(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	c := New(GetConfig(srv.URL, WithRetryPolicy(RetryPolicy{InitialBackoff: time.Hour, MaxBackoff: time.Hour})))
+
+	start := time.Now()
+	_, err := c.DoRequest(ctx, "/pet/1", http.MethodGet, nil)
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("c.DoRequest(...): slept %s past the deadline of its context", d)
+	}
+	var se *ServerException
+	if !errors.As(err, &se) {
+		t.Errorf("c.DoRequest(...): want the last error of the store, got %v", err)
+	}
+	if diff := cmp.Diff(1, calls); diff != "" {
+		t.Errorf("c.DoRequest(...): -want calls, +got calls:\n%s\n", diff)
+	}
+}
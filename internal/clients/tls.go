@@ -0,0 +1,95 @@
+package petstore
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+)
+
+// TLSOptions configures how the pet store endpoint is authenticated, and how
+// the client authenticates to it. All certificates and keys are PEM encoded.
+type TLSOptions struct {
+	// CABundle is trusted to verify the certificate of the pet store, in
+	// addition to the system roots.
+	CABundle []byte
+	// ClientCert and ClientKey are presented to pet stores requiring mutual
+	// TLS. Either both or neither must be set.
+	ClientCert []byte
+	ClientKey  []byte
+	// ServerName overrides the name used to verify the certificate of the
+	// pet store.
+	ServerName string
+}
+
+// A TLSError is returned when a TLS connection to the pet store cannot be
+// established, or when the TLS configuration itself is invalid.
+type TLSError struct {
+	Err error
+}
+
+func (e *TLSError) Error() string { return "TLSError: " + e.Err.Error() }
+func (e *TLSError) Unwrap() error { return e.Err }
+
+func IsErrorTLS(err error) bool {
+	var tlsError *TLSError
+	return errors.As(err, &tlsError)
+}
+
+func (o TLSOptions) isZero() bool {
+	return len(o.CABundle) == 0 && len(o.ClientCert) == 0 && len(o.ClientKey) == 0 && o.ServerName == ""
+}
+
+// config builds a tls.Config from the options, or returns nil if no option
+// is set so that the defaults of the transport apply.
+func (o TLSOptions) config() (*tls.Config, error) {
+	if o.isZero() {
+		return nil, nil
+	}
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: o.ServerName,
+	}
+	if len(o.CABundle) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(o.CABundle) {
+			return nil, &TLSError{Err: errors.New("CA bundle contains no PEM encoded certificate")}
+		}
+		cfg.RootCAs = pool
+	}
+	if len(o.ClientCert) > 0 || len(o.ClientKey) > 0 {
+		cert, err := tls.X509KeyPair(o.ClientCert, o.ClientKey)
+		if err != nil {
+			return nil, &TLSError{Err: err}
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// wrapTLSError wraps errors caused by a failed TLS handshake in a TLSError,
+// so that they can be told apart from other network errors.
+func wrapTLSError(err error) error {
+	var (
+		unknownAuthority x509.UnknownAuthorityError
+		hostname         x509.HostnameError
+		invalid          x509.CertificateInvalidError
+		recordHeader     tls.RecordHeaderError
+		opErr            *net.OpError
+	)
+	switch {
+	case errors.As(err, &unknownAuthority),
+		errors.As(err, &hostname),
+		errors.As(err, &invalid),
+		errors.As(err, &recordHeader):
+		return &TLSError{Err: err}
+	case errors.As(err, &opErr) && opErr.Op == "remote error":
+		// TLS alerts sent by the pet store, for example because it rejected
+		// our client certificate.
+		return &TLSError{Err: err}
+	}
+	return err
+}
@@ -0,0 +1,79 @@
+package petstore
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+
+	type want struct {
+		newErr bool
+		tlsErr bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		o      TLSOptions
+		want   want
+	}{
+		"TrustedCA": {
+			reason: "A request should succeed when the CA of the store is trusted.",
+			o:      TLSOptions{CABundle: ca},
+		},
+		"ServerNameOverride": {
+			reason: "A request should succeed when the certificate matches the overridden server name.",
+			o:      TLSOptions{CABundle: ca, ServerName: "example.com"},
+		},
+		"UnknownAuthority": {
+			reason: "A store signed by an untrusted CA should cause a TLS error.",
+			want:   want{tlsErr: true},
+		},
+		"WrongServerName": {
+			reason: "A certificate that does not match the server name should cause a TLS error.",
+			o:      TLSOptions{CABundle: ca, ServerName: "petstore.invalid"},
+			want:   want{tlsErr: true},
+		},
+		"InvalidBundle": {
+			reason: "A CA bundle without certificates should be rejected.",
+			o:      TLSOptions{CABundle: []byte("not a certificate")},
+			want:   want{newErr: true, tlsErr: true},
+		},
+		"IncompleteKeyPair": {
+			reason: "A client certificate without its key should be rejected.",
+			o:      TLSOptions{ClientCert: ca},
+			want:   want{newErr: true, tlsErr: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			hc, err := NewHTTPClient(TransportOptions{TLS: tc.o})
+			if diff := cmp.Diff(tc.want.newErr, err != nil); diff != "" {
+				t.Fatalf("\n%s\nNewHTTPClient(...): -want error, +got error:\n%s\nerror: %v", tc.reason, diff, err)
+			}
+			if err == nil {
+				c := New(GetConfig(srv.URL, WithHTTPClient(hc), WithRetryPolicy(RetryPolicy{MaxRetries: -1})))
+				var res *http.Response
+				res, err = c.DoRequest(context.Background(), "/pet/1", http.MethodGet, nil)
+				if res != nil {
+					res.Body.Close()
+				}
+			}
+			if diff := cmp.Diff(tc.want.tlsErr, IsErrorTLS(err)); diff != "" {
+				t.Errorf("\n%s\nIsErrorTLS(...): -want, +got:\n%s\nerror: %v", tc.reason, diff, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package petstore
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName is the name of the tracer spans around pet store requests are
+// recorded with.
+const TracerName = "github.com/alexisries/provider-petstore/internal/clients"
+
+// startSpan starts a client span around a request to the pet store. Spans
+// are recorded by the global TracerProvider, which is a no-op unless tracing
+// is enabled.
+func startSpan(ctx context.Context, method, path string) (context.Context, trace.Span) {
+	route := Route(path)
+	return otel.Tracer(TracerName).Start(ctx, method+" "+route,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPMethodKey.String(method),
+			semconv.HTTPRouteKey.String(route),
+		),
+	)
+}
+
+// endSpan records the outcome of a request on its span and ends it.
+func endSpan(span trace.Span, res *http.Response, err error) {
+	defer span.End()
+	code := 0
+	if res != nil {
+		code = res.StatusCode
+	}
+	if c, ok := StatusCode(err); ok {
+		code = c
+	}
+	if code != 0 {
+		span.SetAttributes(semconv.HTTPStatusCodeKey.Int(code))
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+// recordRetry adds an event about an upcoming retry to the span of ctx.
+func recordRetry(ctx context.Context, a RetryAttempt) {
+	attrs := []attribute.KeyValue{
+		attribute.Int("attempt", a.Attempt),
+		attribute.String("wait", a.Wait.String()),
+	}
+	if a.StatusCode != 0 {
+		attrs = append(attrs, semconv.HTTPStatusCodeKey.Int(a.StatusCode))
+	}
+	trace.SpanFromContext(ctx).AddEvent("retry", trace.WithAttributes(attrs...))
+}
+
+// injectTraceContext adds the headers propagating the span of ctx, such as
+// the W3C traceparent header, to the supplied request.
+func injectTraceContext(ctx context.Context, req *http.Request) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+}
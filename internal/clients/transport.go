@@ -0,0 +1,124 @@
+package petstore
+
+import (
+	"net"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRequestTimeout  = 30 * time.Second
+	defaultIdleConnTimeout = 90 * time.Second
+	defaultKeepAlive       = 30 * time.Second
+	defaultMaxIdleConns    = 100
+)
+
+// TransportOptions tunes the HTTP client used to reach a pet store. Zero
+// values are replaced by sensible defaults.
+type TransportOptions struct {
+	RequestTimeout  time.Duration
+	IdleConnTimeout time.Duration
+	KeepAlive       time.Duration
+	MaxIdleConns    int
+	MaxConnsPerHost int
+	TLS             TLSOptions
+	Proxy           ProxyOptions
+}
+
+func (o TransportOptions) withDefaults() TransportOptions {
+	if o.RequestTimeout == 0 {
+		o.RequestTimeout = defaultRequestTimeout
+	}
+	if o.IdleConnTimeout == 0 {
+		o.IdleConnTimeout = defaultIdleConnTimeout
+	}
+	if o.KeepAlive == 0 {
+		o.KeepAlive = defaultKeepAlive
+	}
+	if o.MaxIdleConns == 0 {
+		o.MaxIdleConns = defaultMaxIdleConns
+	}
+	return o
+}
+
+// NewHTTPClient returns an http.Client with its own connection pool, tuned
+// according to the supplied options.
+func NewHTTPClient(o TransportOptions) (*http.Client, error) {
+	o = o.withDefaults()
+	tlsConfig, err := o.TLS.config()
+	if err != nil {
+		return nil, err
+	}
+	proxy, err := o.Proxy.proxyFunc()
+	if err != nil {
+		return nil, err
+	}
+	t := &http.Transport{
+		Proxy: proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: o.KeepAlive,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          o.MaxIdleConns,
+		MaxIdleConnsPerHost:   o.MaxIdleConns,
+		MaxConnsPerHost:       o.MaxConnsPerHost,
+		IdleConnTimeout:       o.IdleConnTimeout,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+	return &http.Client{
+		Transport: t,
+		Timeout:   o.RequestTimeout,
+	}, nil
+}
+
+type cachedHTTPClient struct {
+	generation int64
+	options    TransportOptions
+	client     *http.Client
+}
+
+// An HTTPClientCache hands out one pooled http.Client per ProviderConfig so
+// that connections are reused across reconciles. A client is rebuilt only
+// when the generation of its ProviderConfig changes, or when its TLS material
+// is rotated in the Secrets or ConfigMaps it is read from.
+type HTTPClientCache struct {
+	mu      sync.Mutex
+	clients map[string]cachedHTTPClient
+}
+
+// NewHTTPClientCache returns an empty HTTPClientCache.
+func NewHTTPClientCache() *HTTPClientCache {
+	return &HTTPClientCache{
+		clients: map[string]cachedHTTPClient{},
+	}
+}
+
+// Get returns the http.Client cached for the named ProviderConfig, building a
+// new one from the supplied options if none is cached yet or if the cached
+// one was built for a different generation or different options.
+func (c *HTTPClientCache) Get(name string, generation int64, o TransportOptions) (*http.Client, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached, ok := c.clients[name]
+	if ok && cached.generation == generation && reflect.DeepEqual(cached.options, o) {
+		return cached.client, nil
+	}
+
+	hc, err := NewHTTPClient(o)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		// Requests still in flight keep their connections; only the idle
+		// ones of the outdated pool are released.
+		cached.client.CloseIdleConnections()
+	}
+	c.clients[name] = cachedHTTPClient{generation: generation, options: o, client: hc}
+	return hc, nil
+}
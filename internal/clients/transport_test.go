@@ -0,0 +1,106 @@
+package petstore
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestHTTPClientCache(t *testing.T) {
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+
+	type step struct {
+		name       string
+		generation int64
+		options    TransportOptions
+
+		// wantReused is whether the client last handed out for the name is
+		// returned again.
+		wantReused bool
+		wantErr    bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		steps  []step
+	}{
+		"Reused": {
+			reason: "The client of a ProviderConfig should be reused while its settings do not change.",
+			steps: []step{
+				{name: "a", generation: 1},
+				{name: "a", generation: 1, wantReused: true},
+				{name: "a", generation: 1, wantReused: true},
+			},
+		},
+		"PerProviderConfig": {
+			reason: "Every ProviderConfig should have a client of its own.",
+			steps: []step{
+				{name: "a", generation: 1},
+				{name: "b", generation: 1},
+				{name: "a", generation: 1, wantReused: true},
+				{name: "b", generation: 1, wantReused: true},
+			},
+		},
+		"GenerationChanged": {
+			reason: "The client should be rebuilt when its ProviderConfig changes.",
+			steps: []step{
+				{name: "a", generation: 1},
+				{name: "a", generation: 2},
+				{name: "a", generation: 2, wantReused: true},
+			},
+		},
+		"TransportChanged": {
+			reason: "The client should be rebuilt when its transport settings change.",
+			steps: []step{
+				{name: "a", generation: 1, options: TransportOptions{RequestTimeout: time.Second}},
+				{name: "a", generation: 1, options: TransportOptions{RequestTimeout: time.Minute}},
+				{name: "a", generation: 1, options: TransportOptions{RequestTimeout: time.Minute}, wantReused: true},
+			},
+		},
+		"TLSRotated": {
+			reason: "The client should be rebuilt when the TLS material it is built from changes.",
+			steps: []step{
+				{name: "a", generation: 1},
+				{name: "a", generation: 1, options: TransportOptions{TLS: TLSOptions{CABundle: ca}}},
+				{name: "a", generation: 1, options: TransportOptions{TLS: TLSOptions{CABundle: ca}}, wantReused: true},
+			},
+		},
+		"InvalidSettings": {
+			reason: "Invalid transport settings should be an error, and leave the cached client in place.",
+			steps: []step{
+				{name: "a", generation: 1},
+				{name: "a", generation: 2, options: TransportOptions{Proxy: ProxyOptions{URL: "ftp://proxy"}}, wantErr: true},
+				{name: "a", generation: 1, wantReused: true},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := NewHTTPClientCache()
+			last := map[string]*http.Client{}
+			for i, s := range tc.steps {
+				hc, err := c.Get(s.name, s.generation, s.options)
+				if diff := cmp.Diff(s.wantErr, err != nil); diff != "" {
+					t.Fatalf("\n%s\nstep %d: c.Get(...): -want error, +got error:\n%s\n%v", tc.reason, i, diff, err)
+				}
+				if err != nil {
+					continue
+				}
+				if diff := cmp.Diff(s.wantReused, hc == last[s.name]); diff != "" {
+					t.Errorf("\n%s\nstep %d: c.Get(...): client reused: -want, +got:\n%s\n", tc.reason, i, diff)
+				}
+				if diff := cmp.Diff(s.options.withDefaults().RequestTimeout, hc.Timeout); diff != "" {
+					t.Errorf("\n%s\nstep %d: c.Get(...): timeout: -want, +got:\n%s\n", tc.reason, i, diff)
+				}
+				last[s.name] = hc
+			}
+		})
+	}
+}
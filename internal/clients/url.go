@@ -0,0 +1,74 @@
+package petstore
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// A URLError is returned when the URL of a pet store is invalid.
+type URLError struct {
+	Err error
+}
+
+func (e *URLError) Error() string { return "URLError: " + e.Err.Error() }
+func (e *URLError) Unwrap() error { return e.Err }
+
+func IsErrorURL(err error) bool {
+	var urlError *URLError
+	return errors.As(err, &urlError)
+}
+
+// ParseServerURL parses and validates the URL of a pet store, for example
+// https://petstore.swagger.io/v2. Its path is the base path of every request,
+// and trailing slashes are removed from it.
+func ParseServerURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, &URLError{Err: err}
+	}
+	switch {
+	case u.Scheme != "http" && u.Scheme != "https":
+		return nil, &URLError{Err: fmt.Errorf("URL %q must use the http or https scheme", raw)}
+	case u.Host == "":
+		return nil, &URLError{Err: fmt.Errorf("URL %q has no host", raw)}
+	case u.RawQuery != "" || u.ForceQuery:
+		return nil, &URLError{Err: fmt.Errorf("URL %q must not have a query", raw)}
+	case u.Fragment != "":
+		return nil, &URLError{Err: fmt.Errorf("URL %q must not have a fragment", raw)}
+	}
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = strings.TrimRight(u.RawPath, "/")
+	return u, nil
+}
+
+// Path expands a path template such as /pet/{petId}/uploadImage with the
+// supplied parameters, in order. Each parameter is escaped, so that it always
+// fills a single path segment.
+func Path(template string, params ...string) string {
+	var b strings.Builder
+	for _, segment := range strings.Split(template, "/") {
+		if segment == "" {
+			continue
+		}
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") && len(params) > 0 {
+			segment, params = url.PathEscape(params[0]), params[1:]
+		}
+		b.WriteString("/")
+		b.WriteString(segment)
+	}
+	return b.String()
+}
+
+// endpoint returns the URL of the supplied escaped path, relative to the URL
+// of the pet store.
+func (c *Client) endpoint(path string) string {
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	if c.config.server == nil {
+		return path
+	}
+	return c.config.server.String() + path
+}
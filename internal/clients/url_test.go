@@ -0,0 +1,123 @@
+package petstore
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseServerURL(t *testing.T) {
+	type want struct {
+		url    string
+		urlErr bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		raw    string
+		want   want
+	}{
+		"BasePath": {
+			reason: "A base path should be kept.",
+			raw:    "https://petstore.example.com/api/v3",
+			want:   want{url: "https://petstore.example.com/api/v3"},
+		},
+		"TrailingSlash": {
+			reason: "Trailing slashes should be removed.",
+			raw:    "https://petstore.example.com/v2//",
+			want:   want{url: "https://petstore.example.com/v2"},
+		},
+		"NoScheme": {
+			reason: "A URL without scheme should be rejected.",
+			raw:    "petstore.example.com/v2",
+			want:   want{urlErr: true},
+		},
+		"NoHost": {
+			reason: "A URL without host should be rejected.",
+			raw:    "https:///v2",
+			want:   want{urlErr: true},
+		},
+		"Query": {
+			reason: "A URL with a query should be rejected.",
+			raw:    "https://petstore.example.com/v2?tenant=a",
+			want:   want{urlErr: true},
+		},
+		"Unparsable": {
+			reason: "An unparsable URL should be rejected.",
+			raw:    "https://petstore.example.com:port/v2",
+			want:   want{urlErr: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			u, err := ParseServerURL(tc.raw)
+			got := want{urlErr: IsErrorURL(err)}
+			if u != nil {
+				got.url = u.String()
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nParseServerURL(...): -want, +got:\n%s\nerror: %v", tc.reason, diff, err)
+			}
+		})
+	}
+}
+
+func TestRequestPath(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		server string
+		path   string
+		want   string
+	}{
+		"TrailingSlash": {
+			reason: "A trailing slash in the server URL should not double the slash of the path.",
+			server: "/",
+			path:   Path("/pet/{petId}", "1"),
+			want:   "/pet/1",
+		},
+		"BasePath": {
+			reason: "The path should be joined to the base path of the server URL.",
+			server: "/api/v3/",
+			path:   Path("/pet/{petId}/uploadImage", "1"),
+			want:   "/api/v3/pet/1/uploadImage",
+		},
+		"EscapedParameter": {
+			reason: "A path parameter should be escaped to fill a single segment.",
+			server: "/v2",
+			path:   Path("/pet/{petId}", "../store/order 1?x"),
+			want:   "/v2/pet/..%2Fstore%2Forder%201%3Fx",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var got string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.URL.EscapedPath()
+			}))
+			defer srv.Close()
+
+			c := New(GetConfig(srv.URL + tc.server))
+			res, err := c.DoRequest(context.Background(), tc.path, http.MethodGet, nil)
+			if err != nil {
+				t.Fatalf("\n%s\nc.DoRequest(...): %v", tc.reason, err)
+			}
+			res.Body.Close()
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nrequest path: -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestInvalidServerURL(t *testing.T) {
+	c := New(GetConfig("petstore.example.com"))
+	_, err := c.DoRequest(context.Background(), "/pet/1", http.MethodGet, nil)
+	if !IsErrorURL(err) {
+		t.Errorf("c.DoRequest(...): want URLError, got %v", err)
+	}
+}
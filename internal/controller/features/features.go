@@ -24,4 +24,9 @@ const (
 	// External Secret Stores. See the below design for more details.
 	// https://github.com/crossplane/crossplane/blob/390ddd/design/design-doc-external-secret-stores.md
 	EnableAlphaExternalSecretStores feature.Flag = "EnableAlphaExternalSecretStores"
+
+	// EnableHTTPBodyDump logs the headers and bodies of the requests sent to
+	// pet stores, and of their responses, at debug level. Credentials are
+	// redacted.
+	EnableHTTPBodyDump feature.Flag = "EnableHTTPBodyDump"
 )
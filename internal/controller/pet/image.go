@@ -0,0 +1,196 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pet
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+
+	"github.com/alexisries/provider-petstore/apis/store/v1alpha1"
+	apisv1alpha1 "github.com/alexisries/provider-petstore/apis/v1alpha1"
+)
+
+const (
+	errFmtGetImage      = "cannot get content of image %q"
+	errFmtUploadImage   = "cannot upload image %q"
+	errReadImage        = "cannot read image file"
+	errNoImageSource    = "no image source is selected"
+	errNoImageDirectory = "images cannot be read from paths unless the ProviderConfig sets an image directory"
+	errImageOutside     = "image path resolves outside of the image directory"
+
+	errFmtImageNamespace = "images cannot be read from %ss in namespace %q unless it is one of the image source namespaces of the ProviderConfig"
+)
+
+// An imageUpload is an image whose content or metadata changed since it was
+// last uploaded.
+type imageUpload struct {
+	image   v1alpha1.PetImage
+	content []byte
+	hash    string
+}
+
+// staleImages returns the images of the supplied pet whose content or
+// metadata differ from those last uploaded, along with their content.
+func (c *external) staleImages(ctx context.Context, cr *v1alpha1.Pet) ([]imageUpload, error) {
+	uploaded := make(map[string]v1alpha1.PetImageObservation, len(cr.Status.AtProvider.Images))
+	for _, o := range cr.Status.AtProvider.Images {
+		uploaded[o.Name] = o
+	}
+
+	var stale []imageUpload
+	for _, img := range cr.Spec.ForProvider.Images {
+		content, err := c.imageContent(ctx, img.Source)
+		if err != nil {
+			return nil, errors.Wrapf(err, errFmtGetImage, img.Name)
+		}
+		h := imageHash(content)
+		if o, ok := uploaded[img.Name]; ok && o.SHA256 == h && o.AdditionalMetadata == img.AdditionalMetadata {
+			continue
+		}
+		stale = append(stale, imageUpload{image: img, content: content, hash: h})
+	}
+	return stale, nil
+}
+
+// uploadImages uploads the stale images of the supplied pet, and records each
+// of them as uploaded as soon as it is. Images no longer in the spec are
+// forgotten.
+func (c *external) uploadImages(ctx context.Context, cr *v1alpha1.Pet) error {
+	stale, err := c.staleImages(ctx, cr)
+	if err != nil {
+		return err
+	}
+
+	images := map[string]bool{}
+	for _, img := range cr.Spec.ForProvider.Images {
+		images[img.Name] = true
+	}
+	var uploaded []v1alpha1.PetImageObservation
+	for _, o := range cr.Status.AtProvider.Images {
+		if images[o.Name] {
+			uploaded = append(uploaded, o)
+		}
+	}
+	cr.Status.AtProvider.Images = uploaded
+
+	for _, u := range stale {
+		_, err := c.service.UploadImage(ctx, meta.GetExternalName(cr), u.image.Name, u.content, u.image.AdditionalMetadata)
+		cr.SetConditions(storeAPICondition(err))
+		if err != nil {
+			return errors.Wrapf(err, errFmtUploadImage, u.image.Name)
+		}
+		recordImage(cr, v1alpha1.PetImageObservation{Name: u.image.Name, SHA256: u.hash, AdditionalMetadata: u.image.AdditionalMetadata})
+	}
+	return nil
+}
+
+// recordImage records the supplied image as uploaded, replacing any previous
+// upload of an image of the same name.
+func recordImage(cr *v1alpha1.Pet, o v1alpha1.PetImageObservation) {
+	for i := range cr.Status.AtProvider.Images {
+		if cr.Status.AtProvider.Images[i].Name == o.Name {
+			cr.Status.AtProvider.Images[i] = o
+			return
+		}
+	}
+	cr.Status.AtProvider.Images = append(cr.Status.AtProvider.Images, o)
+}
+
+// imageContent returns the content of the image selected by the supplied
+// source.
+func (c *external) imageContent(ctx context.Context, src v1alpha1.PetImageSource) ([]byte, error) {
+	switch {
+	case src.ConfigMapKeyRef != nil:
+		ref := src.ConfigMapKeyRef
+		if !c.imageSourceNamespace(ref.Namespace) {
+			return nil, errors.Errorf(errFmtImageNamespace, "ConfigMap", ref.Namespace)
+		}
+		// Images are binary, so only binaryData is read.
+		cm := &corev1.ConfigMap{}
+		if err := c.kube.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, cm); err != nil {
+			return nil, errors.Wrap(err, errGetConfigMap)
+		}
+		v, ok := cm.BinaryData[ref.Key]
+		if !ok {
+			return nil, errors.Errorf(errFmtKeyNotFound, ref.Key, "binaryData of ConfigMap", ref.Namespace, ref.Name)
+		}
+		return v, nil
+	case src.SecretKeyRef != nil:
+		if !c.imageSourceNamespace(src.SecretKeyRef.Namespace) {
+			return nil, errors.Errorf(errFmtImageNamespace, "Secret", src.SecretKeyRef.Namespace)
+		}
+		return resolveKey(ctx, c.kube, &apisv1alpha1.KeySelector{SecretKeyRef: src.SecretKeyRef})
+	case src.Path != "":
+		path, err := c.imagePath(src.Path)
+		if err != nil {
+			return nil, err
+		}
+		content, err := os.ReadFile(path) //nolint:gosec // The path is confined to the image directory.
+		return content, errors.Wrap(err, errReadImage)
+	}
+	return nil, errors.New(errNoImageSource)
+}
+
+// imageSourceNamespace reports whether images may be read from Secrets and
+// ConfigMaps of the supplied namespace.
+func (c *external) imageSourceNamespace(ns string) bool {
+	for _, allowed := range c.imageSourceNamespaces {
+		if ns == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// imagePath resolves the supplied image path within the image directory,
+// following symbolic links, and makes sure it does not leave it.
+func (c *external) imagePath(p string) (string, error) {
+	if c.imageDir == "" {
+		return "", errors.New(errNoImageDirectory)
+	}
+	dir, err := filepath.EvalSymlinks(c.imageDir)
+	if err != nil {
+		return "", errors.Wrap(err, errReadImage)
+	}
+	// Cleaning the path as an absolute one drops the .. elements it holds,
+	// but symbolic links may still lead out of the image directory.
+	path, err := filepath.EvalSymlinks(filepath.Join(dir, filepath.Clean("/"+p)))
+	if err != nil {
+		return "", errors.Wrap(err, errReadImage)
+	}
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", errors.New(errImageOutside)
+	}
+	return path, nil
+}
+
+// imageHash returns the hex encoded SHA-256 hash of the supplied content.
+func imageHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
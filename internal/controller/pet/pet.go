@@ -19,15 +19,20 @@ package pet
 import (
 	"context"
 	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
 
 	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
 	"github.com/crossplane/crossplane-runtime/pkg/connection"
 	"github.com/crossplane/crossplane-runtime/pkg/controller"
 	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
 	"github.com/crossplane/crossplane-runtime/pkg/meta"
 	"github.com/crossplane/crossplane-runtime/pkg/ratelimiter"
 	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
@@ -41,16 +46,49 @@ import (
 )
 
 const (
-	errSDK          = "empty pet returned from client"
-	errNotPet       = "managed resource is not a Pet custom resource"
-	errGetPet       = "cannot get pet"
-	errCreatePet    = "cannot create pet"
-	errUpdatePet    = "cannot update pet"
-	errDeletePet    = "cannot delete pet"
-	errTrackPCUsage = "cannot track ProviderConfig usage"
-	errGetPC        = "cannot get ProviderConfig"
-	// errGetCreds     = "cannot get credentials"
-	// errNewClient    = "cannot create new Service"
+	errSDK             = "empty pet returned from client"
+	errNotPet          = "managed resource is not a Pet custom resource"
+	errGetPet          = "cannot get pet"
+	errCreatePet       = "cannot create pet"
+	errGetPendingPet   = "cannot check whether pending pet was created"
+	errRecordPending   = "cannot record pending pet creation"
+	errNewPetID        = "cannot pick an ID for the new pet"
+	errFmtNoFreePetID  = "no free pet ID found in %d attempts"
+	errUpdatePet       = "cannot update pet"
+	errDeletePet       = "cannot delete pet"
+	errTrackPCUsage    = "cannot track ProviderConfig usage"
+	errGetPC           = "cannot get ProviderConfig"
+	errParseURL        = "cannot parse ProviderConfig URL"
+	errGetCreds        = "cannot get credentials"
+	errEmptyCreds      = "credentials are empty"
+	errGetClientSecret = "cannot get OAuth2 client secret"
+	errGetTLS          = "cannot get TLS configuration"
+	errFmtGetHeader    = "cannot get value of header %q"
+	errNewHTTPClient   = "cannot create HTTP client"
+	errGetSecret       = "cannot get Secret"
+	errGetConfigMap    = "cannot get ConfigMap"
+	errNoKeySource     = "neither a Secret nor a ConfigMap key is selected"
+	errFmtKeyNotFound  = "key %q not found in %s %s/%s"
+
+	// annotationKeyPendingPetID records the ID of a pet whose creation was
+	// requested but not yet confirmed by setting the external name.
+	annotationKeyPendingPetID = "petstore.crossplane.io/pending-pet-id"
+
+	// annotationKeyPendingPetTag records the marker tag of a pet whose
+	// creation was requested with the server ID strategy but not yet
+	// confirmed by setting the external name.
+	annotationKeyPendingPetTag = "petstore.crossplane.io/pending-pet-tag"
+
+	// pendingPetTagPrefix prefixes the UID of a managed resource to form the
+	// marker tag of the pets it creates with the server ID strategy.
+	pendingPetTagPrefix = "crossplane-"
+
+	// maxPetIDAttempts bounds the number of random IDs tried for a new pet
+	// with the client ID strategy.
+	maxPetIDAttempts = 5
+
+	reasonRetryingRequest event.Reason = "RetryingRequest"
+	reasonUnknownFields   event.Reason = "UnknownResponseFields"
 )
 
 // Setup adds a controller that reconciles Pet managed resources.
@@ -62,21 +100,33 @@ func Setup(mgr ctrl.Manager, o controller.Options) error {
 		cps = append(cps, connection.NewDetailsManager(mgr.GetClient(), apisv1alpha1.StoreConfigGroupVersionKind))
 	}
 
+	log := o.Logger.WithValues("controller", name)
+	recorder := event.NewAPIRecorder(mgr.GetEventRecorderFor(name))
+
 	r := managed.NewReconciler(mgr,
 		resource.ManagedKind(v1alpha1.PetGroupVersionKind),
-		managed.WithExternalConnecter(&connector{
+		managed.WithExternalConnecter(&tracingConnector{&connector{
 			kube:         mgr.GetClient(),
 			usage:        resource.NewProviderConfigUsageTracker(mgr.GetClient(), &apisv1alpha1.ProviderConfigUsage{}),
-			newServiceFn: petc.NewClient}),
-		managed.WithLogger(o.Logger.WithValues("controller", name)),
-		managed.WithRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor(name))),
+			logger:       log,
+			recorder:     recorder,
+			httpClients:  petstore.NewHTTPClientCache(),
+			tokenSources: petstore.NewTokenSourceCache(),
+			limiters:     petstore.NewLimiterCache(),
+			breakers:     petstore.NewBreakerCache(petstore.BreakerOptions{}),
+			caches:       petstore.NewResponseCaches(),
+			patches:      petc.NewPatchSupport(),
+			dumpBodies:   o.Features.Enabled(features.EnableHTTPBodyDump),
+			newServiceFn: petc.NewClient}}),
+		managed.WithLogger(log),
+		managed.WithRecorder(recorder),
 		managed.WithConnectionPublishers(cps...))
 
 	return ctrl.NewControllerManagedBy(mgr).
 		Named(name).
 		WithOptions(o.ForControllerRuntime()).
 		For(&v1alpha1.Pet{}).
-		Complete(ratelimiter.NewReconciler(name, r, o.GlobalRateLimiter))
+		Complete(ratelimiter.NewReconciler(name, &tracingReconciler{r}, o.GlobalRateLimiter))
 }
 
 // A connector is expected to produce an ExternalClient when its Connect method
@@ -84,6 +134,15 @@ func Setup(mgr ctrl.Manager, o controller.Options) error {
 type connector struct {
 	kube         client.Client
 	usage        resource.Tracker
+	logger       logging.Logger
+	recorder     event.Recorder
+	httpClients  *petstore.HTTPClientCache
+	tokenSources *petstore.TokenSourceCache
+	limiters     *petstore.LimiterCache
+	breakers     *petstore.BreakerCache
+	caches       *petstore.ResponseCaches
+	patches      *petc.PatchSupport
+	dumpBodies   bool
 	newServiceFn func(*petstore.Config) petc.Client
 }
 
@@ -106,17 +165,104 @@ func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.E
 	if err := c.kube.Get(ctx, types.NamespacedName{Name: cr.GetProviderConfigReference().Name}, pc); err != nil {
 		return nil, errors.Wrap(err, errGetPC)
 	}
-	/*
-		cd := pc.Spec.Credentials
-		data, err := resource.CommonCredentialExtractor(ctx, cd.Source, c.kube, cd.CommonCredentialSelectors)
-		if err != nil {
-			return nil, errors.Wrap(err, errGetCreds)
+
+	server, err := petstore.ParseServerURL(pc.Spec.ServerUrl)
+	if err != nil {
+		c.setCondition(ctx, pc, apisv1alpha1.ConfigInvalid(apisv1alpha1.ReasonInvalidURL, err.Error()))
+		cr.SetConditions(v1alpha1.StoreAPIFailed(v1alpha1.ReasonInvalidProviderConfig, err))
+		return nil, errors.Wrap(err, errParseURL)
+	}
+	c.setCondition(ctx, pc, apisv1alpha1.ConfigValid())
+
+	opts, err := c.clientOptions(ctx, cr, pc)
+	if err != nil {
+		return nil, err
+	}
+	breaker := c.breakers.Get(server.String())
+	c.reportStoreAvailability(ctx, pc, server, breaker.State())
+	opts = append(opts,
+		petstore.WithCircuitBreaker(breaker),
+		petstore.WithRetryNotifier(c.retryNotifier(cr)),
+		petstore.WithLogger(c.logger.WithValues("request", cr.GetName(), "providerConfig", pc.GetName())),
+		petstore.WithBodyDump(c.dumpBodies),
+	)
+	svc := c.newServiceFn(petstore.GetConfig(server.String(), opts...))
+
+	return &external{
+		kube:                  c.kube,
+		service:               svc,
+		idStrategy:            petc.IDStrategy(pc.Spec.IDStrategy),
+		imageDir:              pc.Spec.ImageDirectory,
+		imageSourceNamespaces: pc.Spec.ImageSourceNamespaces,
+		newPetID:              petc.NewPetID,
+		patches:               c.patches,
+		server:                server.String(),
+	}, nil
+}
+
+// reportStoreAvailability reflects the state of the circuit breaker of the
+// pet store in a condition of the supplied ProviderConfig.
+func (c *connector) reportStoreAvailability(ctx context.Context, pc *apisv1alpha1.ProviderConfig, server *url.URL, state petstore.BreakerState) {
+	cond := apisv1alpha1.StoreAvailable()
+	switch state {
+	case petstore.BreakerOpen:
+		cond = apisv1alpha1.StoreUnavailable(apisv1alpha1.ReasonCircuitOpen,
+			fmt.Sprintf("Requests to %s are failed fast because it kept failing", server))
+	case petstore.BreakerHalfOpen:
+		cond = apisv1alpha1.StoreUnavailable(apisv1alpha1.ReasonCircuitHalfOpen,
+			fmt.Sprintf("Probing whether %s recovered", server))
+	case petstore.BreakerClosed:
+	}
+	c.setCondition(ctx, pc, cond)
+}
+
+// setCondition sets the supplied condition on a ProviderConfig. Its status is
+// only updated when the condition changes. Failing to update it is logged, but
+// does not prevent the managed resource from being reconciled.
+func (c *connector) setCondition(ctx context.Context, pc *apisv1alpha1.ProviderConfig, cond xpv1.Condition) {
+	if pc.GetCondition(cond.Type).Equal(cond) {
+		return
+	}
+	pc.SetConditions(cond)
+	if err := c.kube.Status().Update(ctx, pc); err != nil {
+		c.logger.Info("Cannot update ProviderConfig status", "providerConfig", pc.GetName(), "error", err.Error())
+	}
+}
+
+// retryNotifier returns a function that logs and records an event on the
+// supplied managed resource whenever one of its requests is retried, making
+// flaky pet stores visible to operators.
+func (c *connector) retryNotifier(mg resource.Managed) petstore.RetryNotifier {
+	return func(_ context.Context, a petstore.RetryAttempt) {
+		cause := a.Err
+		if cause == nil {
+			cause = errors.Errorf("pet store responded with status %d", a.StatusCode)
 		}
-	*/
-	petStoreConfig := petstore.GetConfig(pc.Spec.ServerUrl)
-	svc := c.newServiceFn(petStoreConfig)
+		c.logger.Info("Retrying pet store request",
+			"method", a.Method,
+			"path", a.Path,
+			"attempt", a.Attempt,
+			"wait", a.Wait.String(),
+			"cause", cause.Error(),
+			"resource", mg.GetName())
+		c.recorder.Event(mg, event.Warning(reasonRetryingRequest,
+			errors.Wrapf(cause, "retrying %s %s (attempt %d) in %s", a.Method, a.Path, a.Attempt, a.Wait)))
+	}
+}
 
-	return &external{service: svc}, nil
+// unknownFieldsNotifier returns a function that records a warning event on
+// the supplied managed resource whenever a response received on its behalf
+// has fields the provider does not know.
+func (c *connector) unknownFieldsNotifier(mg resource.Managed) petstore.UnknownFieldsNotifier {
+	return func(_ context.Context, method, path string, fields []string) {
+		c.logger.Debug("Unknown fields in pet store response",
+			"method", method,
+			"path", path,
+			"fields", fields,
+			"resource", mg.GetName())
+		c.recorder.Event(mg, event.Warning(reasonUnknownFields,
+			errors.Errorf("%s %s returned fields unknown to the provider: %s", method, path, strings.Join(fields, ", "))))
+	}
 }
 
 // An ExternalClient observes, then either creates, updates, or deletes an
@@ -125,6 +271,20 @@ type external struct {
 	// A 'client' used to connect to the external resource API. In practice this
 	// would be something like an AWS SDK client.
 	service petc.Client
+
+	kube       client.Client
+	idStrategy petc.IDStrategy
+	newPetID   func() (int64, error)
+	imageDir   string
+
+	// imageSourceNamespaces are the namespaces of the Secrets and ConfigMaps
+	// images may be read from.
+	imageSourceNamespaces []string
+
+	// patches remembers whether the pet store at server supports JSON
+	// merge patches.
+	patches *petc.PatchSupport
+	server  string
 }
 
 func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
@@ -134,12 +294,11 @@ func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.Ex
 	}
 
 	if meta.GetExternalName(cr) == "" {
-		return managed.ExternalObservation{
-			ResourceExists: false,
-		}, nil
+		return c.observePending(ctx, cr)
 	}
 
-	pet, err := c.service.GetPetById(meta.GetExternalName(cr))
+	pet, err := c.service.GetPetById(ctx, meta.GetExternalName(cr))
+	cr.SetConditions(storeAPICondition(err))
 	if err != nil {
 		if petstore.IsErrorNotFound(err) {
 			return managed.ExternalObservation{ResourceExists: false}, nil
@@ -153,39 +312,203 @@ func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.Ex
 
 	// current := cr.Spec.ForProvider.DeepCopy()
 
-	cr.Status.AtProvider = petc.GeneratePetStatus(pet)
+	setAtProvider(cr, pet)
+	upToDate := isPetUpToDate(cr, pet)
+	if upToDate {
+		stale, err := c.staleImages(ctx, cr)
+		if err != nil {
+			return managed.ExternalObservation{}, err
+		}
+		upToDate = len(stale) == 0
+	}
 
 	return managed.ExternalObservation{
 		ResourceExists:   true,
-		ResourceUpToDate: petc.IsPetUptodate(cr.Spec.ForProvider, pet),
-		// ResourceLateInitialized: !cmp.Equal(current, &cr.Spec.ForProvider),
+		ResourceUpToDate: upToDate,
+		// The pending creation marker is no longer needed once the external
+		// name is known. Reporting it as late initialization persists its
+		// removal.
+		ResourceLateInitialized: removePendingPetID(cr),
 	}, nil
 }
 
+// observePending checks whether a pet whose creation was requested by an
+// earlier reconcile exists, even though its ID was never recorded as external
+// name, for example because the response of the pet store was lost. Such a pet
+// is adopted rather than created again.
+func (c *external) observePending(ctx context.Context, cr *v1alpha1.Pet) (managed.ExternalObservation, error) {
+	if id, ok := pendingPetID(cr); ok {
+		return c.observePendingID(ctx, cr, id)
+	}
+	if tag, ok := cr.GetAnnotations()[annotationKeyPendingPetTag]; ok {
+		return c.observePendingTag(ctx, cr, tag)
+	}
+	return managed.ExternalObservation{ResourceExists: false}, nil
+}
+
+// observePendingID checks whether the pet with the supplied ID, picked by the
+// client, was created.
+func (c *external) observePendingID(ctx context.Context, cr *v1alpha1.Pet, id int64) (managed.ExternalObservation, error) {
+	pet, err := c.service.GetPetById(ctx, strconv.FormatInt(id, 10))
+	cr.SetConditions(storeAPICondition(err))
+	if err != nil {
+		if petstore.IsErrorNotFound(err) {
+			return managed.ExternalObservation{ResourceExists: false}, nil
+		}
+		return managed.ExternalObservation{}, errors.Wrap(err, errGetPendingPet)
+	}
+	if pet == nil {
+		return managed.ExternalObservation{}, errors.New(errSDK)
+	}
+	if pet.Name != cr.Spec.ForProvider.Name {
+		// Another pet has the ID; ours was never created. Create picks a
+		// new ID.
+		removePendingPetID(cr)
+		return managed.ExternalObservation{ResourceExists: false}, nil
+	}
+	return adoptPending(cr, pet), nil
+}
+
+// observePendingTag checks whether a pet carrying the supplied marker tag,
+// whose ID was to be assigned by the store, was created. The store may list
+// pets carrying only some of the tags asked for, so the marker and the name of
+// each pet found are checked again.
+func (c *external) observePendingTag(ctx context.Context, cr *v1alpha1.Pet, tag string) (managed.ExternalObservation, error) {
+	var found *petc.Pet
+	err := c.service.FindPetsByTags(ctx, []string{tag}, func(p *petc.Pet) error {
+		if found == nil && p.Id != nil && p.Name == cr.Spec.ForProvider.Name && hasTag(p, tag) {
+			found = p
+		}
+		return nil
+	})
+	cr.SetConditions(storeAPICondition(err))
+	if err != nil {
+		return managed.ExternalObservation{}, errors.Wrap(err, errGetPendingPet)
+	}
+	if found == nil {
+		// The creation did not succeed. Create tries again with the same
+		// marker tag.
+		return managed.ExternalObservation{ResourceExists: false}, nil
+	}
+	return adoptPending(cr, found), nil
+}
+
+// adoptPending records the supplied pet, whose creation was pending, as the
+// external resource of the supplied managed resource.
+func adoptPending(cr *v1alpha1.Pet, pet *petc.Pet) managed.ExternalObservation {
+	meta.SetExternalName(cr, strconv.FormatInt(*pet.Id, 10))
+	removePendingPetID(cr)
+	setAtProvider(cr, pet)
+	setApplied(cr)
+	return managed.ExternalObservation{
+		ResourceExists:          true,
+		ResourceUpToDate:        isPetUpToDate(cr, pet),
+		ResourceLateInitialized: true,
+	}
+}
+
 func (c *external) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
 	cr, ok := mg.(*v1alpha1.Pet)
 	if !ok {
 		return managed.ExternalCreation{}, errors.New(errNotPet)
 	}
 
-	pet, err := c.service.AddPet(petc.GeneratePet(cr.Spec.ForProvider))
+	p := petc.GeneratePet(cr.Spec.ForProvider)
+	if err := c.recordPending(ctx, cr, p); err != nil {
+		return managed.ExternalCreation{}, err
+	}
+
+	pet, err := c.service.AddPet(ctx, p, idempotencyKey(cr, p.Id))
+	cr.SetConditions(storeAPICondition(err))
 	if err != nil {
 		return managed.ExternalCreation{}, errors.Wrap(err, errCreatePet)
 	}
-	meta.SetExternalName(cr, fmt.Sprint(*pet.Id))
+	if pet == nil || pet.Id == nil {
+		return managed.ExternalCreation{}, errors.New(errSDK)
+	}
+	meta.SetExternalName(cr, strconv.FormatInt(*pet.Id, 10))
+	setAtProvider(cr, pet)
+	setApplied(cr)
 	return managed.ExternalCreation{}, nil
 }
 
+// recordPending records how the supplied pet, about to be created, can be found
+// by a later reconcile if the outcome of its creation is unknown. With the
+// client ID strategy that is its ID. With the server ID strategy, a marker tag
+// derived from the UID of the managed resource is added to the pet. The first
+// update after its ID is recorded removes the marker from the pet.
+func (c *external) recordPending(ctx context.Context, cr *v1alpha1.Pet, p *petc.Pet) error {
+	if c.idStrategy == petc.IDStrategyClient {
+		id, err := c.clientPetID(ctx, cr)
+		if err != nil {
+			return err
+		}
+		p.Id = &id
+		return nil
+	}
+	tag, ok := cr.GetAnnotations()[annotationKeyPendingPetTag]
+	if !ok {
+		tag = pendingPetTag(cr)
+		meta.AddAnnotations(cr, map[string]string{annotationKeyPendingPetTag: tag})
+		if err := c.kube.Update(ctx, cr); err != nil {
+			return errors.Wrap(err, errRecordPending)
+		}
+	}
+	*p.Tags = append(*p.Tags, petc.Tag{Name: petstore.String(tag)})
+	return nil
+}
+
+// clientPetID returns the ID of a pet created with the client ID strategy. The
+// ID is recorded before the pet is created, so that a later reconcile can find
+// the pet if the outcome of the creation is unknown.
+func (c *external) clientPetID(ctx context.Context, cr *v1alpha1.Pet) (int64, error) {
+	if id, ok := pendingPetID(cr); ok {
+		return id, nil
+	}
+	id, err := c.freePetID(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, errNewPetID)
+	}
+	meta.AddAnnotations(cr, map[string]string{annotationKeyPendingPetID: strconv.FormatInt(id, 10)})
+	if err := c.kube.Update(ctx, cr); err != nil {
+		return 0, errors.Wrap(err, errRecordPending)
+	}
+	return id, nil
+}
+
+// freePetID returns a random ID that no pet of the store has yet.
+func (c *external) freePetID(ctx context.Context) (int64, error) {
+	for i := 0; i < maxPetIDAttempts; i++ {
+		id, err := c.newPetID()
+		if err != nil {
+			return 0, err
+		}
+		_, err = c.service.GetPetById(ctx, strconv.FormatInt(id, 10))
+		if petstore.IsErrorNotFound(err) {
+			return id, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return 0, errors.Errorf(errFmtNoFreePetID, maxPetIDAttempts)
+}
+
 func (c *external) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
 	cr, ok := mg.(*v1alpha1.Pet)
 	if !ok {
 		return managed.ExternalUpdate{}, errors.New(errNotPet)
 	}
 
-	pet := petc.GeneratePet(cr.Spec.ForProvider)
-	err := c.service.UpdatePetById(meta.GetExternalName(cr), pet)
+	pet, err := c.updatePet(ctx, cr)
 	if err != nil {
-		return managed.ExternalUpdate{}, errors.Wrap(err, errUpdatePet)
+		return managed.ExternalUpdate{}, err
+	}
+	setAtProvider(cr, pet)
+	setApplied(cr)
+
+	if err := c.uploadImages(ctx, cr); err != nil {
+		return managed.ExternalUpdate{}, err
 	}
 
 	return managed.ExternalUpdate{}, nil
@@ -197,6 +520,199 @@ func (c *external) Delete(ctx context.Context, mg resource.Managed) error {
 		return errors.New(errNotPet)
 	}
 
-	err := c.service.DeletePetById(meta.GetExternalName(cr))
+	err := c.service.DeletePetById(ctx, meta.GetExternalName(cr))
+	cr.SetConditions(storeAPICondition(err))
 	return errors.Wrap(resource.Ignore(petstore.IsErrorNotFound, err), errDeletePet)
 }
+
+// storeAPIReasons map the errors returned by the pet store client to the
+// reason of the StoreAPI condition, in order of precedence.
+var storeAPIReasons = []struct {
+	is     func(error) bool
+	reason xpv1.ConditionReason
+}{
+	{is: petstore.IsErrorValidation, reason: v1alpha1.ReasonInvalidSpec},
+	{is: petstore.IsErrorAccessDenied, reason: v1alpha1.ReasonAccessDenied},
+	{is: petstore.IsErrorConflict, reason: v1alpha1.ReasonConflict},
+	{is: petstore.IsErrorThrottling, reason: v1alpha1.ReasonThrottled},
+	{is: petstore.IsErrorServer, reason: v1alpha1.ReasonStoreError},
+	{is: petstore.IsErrorTLS, reason: v1alpha1.ReasonTLSError},
+	{is: petstore.IsErrorStoreUnavailable, reason: v1alpha1.ReasonStoreUnavailable},
+	{is: petstore.IsErrorDecode, reason: v1alpha1.ReasonInvalidResponse},
+}
+
+// storeAPICondition maps the outcome of a request made to the pet store to a
+// condition, so that operators can tell a bad spec apart from a store that is
+// down. A pet that does not exist is an expected answer, not a failure.
+func storeAPICondition(err error) xpv1.Condition {
+	if err == nil || petstore.IsErrorNotFound(err) {
+		return v1alpha1.StoreAPISucceeded()
+	}
+	for _, r := range storeAPIReasons {
+		if r.is(err) {
+			return v1alpha1.StoreAPIFailed(r.reason, err)
+		}
+	}
+	return v1alpha1.StoreAPIFailed(v1alpha1.ReasonRequestFailed, err)
+}
+
+// updatePet brings the fields of the pet in the store that the supplied
+// managed resource manages up to date with the smallest update that does, and
+// returns the pet as updated. Fields and tags it does not manage are left as
+// they are. A pet whose name alone drifted is renamed with a form; any other
+// drift is sent as a JSON merge patch, or applied to the current pet and put
+// back on stores that do not support patches.
+func (c *external) updatePet(ctx context.Context, cr *v1alpha1.Pet) (*petc.Pet, error) {
+	id := meta.GetExternalName(cr)
+	// The pet observed may have been served from the cache; the update must
+	// not be based on a stale one.
+	current, err := c.service.GetPetByIdUncached(ctx, id)
+	cr.SetConditions(storeAPICondition(err))
+	if err != nil {
+		return nil, errors.Wrap(err, errGetPet)
+	}
+	if current == nil {
+		return nil, errors.New(errSDK)
+	}
+
+	patch := petc.GeneratePetPatch(cr.Spec.ForProvider, cr.Status.AtProvider, current)
+	if marker := pendingPetTag(cr); hasTag(current, marker) {
+		// The marker tag of the creation of the pet is not needed once its
+		// ID is recorded as external name.
+		tags := current.Tags
+		if patch.Tags != nil {
+			tags = patch.Tags
+		}
+		stripped := withoutTag(*tags, marker)
+		patch.Tags = &stripped
+	}
+	switch {
+	case patch.IsEmpty():
+		// Only the images of the pet drifted.
+		return current, nil
+	case patch.IsNameOnly():
+		err := c.service.UpdatePetWithForm(ctx, id, *patch.Name, "")
+		cr.SetConditions(storeAPICondition(err))
+		if err != nil {
+			return nil, errors.Wrap(err, errUpdatePet)
+		}
+		return patch.Apply(current), nil
+	case c.patches.Supported(c.server):
+		pet, err := c.service.PatchPet(ctx, id, patch)
+		if !petc.IsErrorPatchUnsupported(err) {
+			return updatedPet(cr, pet, err)
+		}
+		c.patches.SetUnsupported(c.server)
+	}
+
+	pet, err := c.service.UpdatePetById(ctx, id, patch.Apply(current))
+	return updatedPet(cr, pet, err)
+}
+
+// updatedPet reflects the outcome of an update of the supplied pet in the
+// conditions of the supplied managed resource.
+func updatedPet(cr *v1alpha1.Pet, pet *petc.Pet, err error) (*petc.Pet, error) {
+	cr.SetConditions(storeAPICondition(err))
+	if err != nil {
+		return nil, errors.Wrap(err, errUpdatePet)
+	}
+	if pet == nil {
+		return nil, errors.New(errSDK)
+	}
+	return pet, nil
+}
+
+// setAtProvider records the observed state of the supplied pet. The images
+// uploaded for it, and the photo URLs and tags last applied to it, are kept
+// since the pet store cannot tell them.
+func setAtProvider(cr *v1alpha1.Pet, pet *petc.Pet) {
+	o := cr.Status.AtProvider
+	cr.Status.AtProvider = petc.GeneratePetStatus(pet)
+	cr.Status.AtProvider.Images = o.Images
+	cr.Status.AtProvider.ManagedPhotoUrls = o.ManagedPhotoUrls
+	cr.Status.AtProvider.ManagedTagIds = o.ManagedTagIds
+}
+
+// setApplied records the photo URLs and tags of the parameters of the
+// supplied pet as last applied to it, so that those later dropped from its
+// parameters can be told from those someone else added.
+func setApplied(cr *v1alpha1.Pet) {
+	p := cr.Spec.ForProvider
+	var ids []int64
+	for _, t := range p.Tags {
+		ids = append(ids, t.Id)
+	}
+	cr.Status.AtProvider.ManagedTagIds = ids
+	cr.Status.AtProvider.ManagedPhotoUrls = append([]string(nil), p.PhotoUrls...)
+}
+
+// pendingPetID returns the ID of the pet whose creation was requested but not
+// yet confirmed, if any.
+func pendingPetID(cr *v1alpha1.Pet) (int64, bool) {
+	v, ok := cr.GetAnnotations()[annotationKeyPendingPetID]
+	if !ok {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(v, 10, 64)
+	return id, err == nil
+}
+
+// removePendingPetID removes the pending creation markers of the supplied pet,
+// and reports whether there were any.
+func removePendingPetID(cr *v1alpha1.Pet) bool {
+	a := cr.GetAnnotations()
+	_, id := a[annotationKeyPendingPetID]
+	_, tag := a[annotationKeyPendingPetTag]
+	if !id && !tag {
+		return false
+	}
+	meta.RemoveAnnotations(cr, annotationKeyPendingPetID, annotationKeyPendingPetTag)
+	return true
+}
+
+// isPetUpToDate reports whether the supplied pet is up to date with the
+// supplied managed resource. It is not while it carries the marker tag of its
+// creation.
+func isPetUpToDate(cr *v1alpha1.Pet, pet *petc.Pet) bool {
+	return petc.IsPetUptodate(cr.Spec.ForProvider, cr.Status.AtProvider, pet) && !hasTag(pet, pendingPetTag(cr))
+}
+
+// pendingPetTag returns the marker tag of the pets the supplied managed
+// resource creates with the server ID strategy.
+func pendingPetTag(cr *v1alpha1.Pet) string {
+	return pendingPetTagPrefix + string(cr.GetUID())
+}
+
+// withoutTag returns the supplied tags without those with the supplied name.
+func withoutTag(tags []petc.Tag, name string) []petc.Tag {
+	out := make([]petc.Tag, 0, len(tags))
+	for _, t := range tags {
+		if t.Name == nil || *t.Name != name {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// hasTag reports whether the supplied pet has a tag with the supplied name.
+func hasTag(p *petc.Pet, name string) bool {
+	if p.Tags == nil {
+		return false
+	}
+	for _, t := range *p.Tags {
+		if t.Name != nil && *t.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// idempotencyKey derives the Idempotency-Key of the creation of a pet from the
+// UID of its managed resource and, when the client picks it, its ID, so that
+// every retry of the same creation sends the same key.
+func idempotencyKey(cr *v1alpha1.Pet, id *int64) string {
+	if id == nil {
+		return string(cr.GetUID())
+	}
+	return fmt.Sprintf("%s-%d", cr.GetUID(), *id)
+}
@@ -18,14 +18,22 @@ package pet
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strconv"
 	"testing"
 
 	"github.com/alexisries/provider-petstore/apis/store/v1alpha1"
+	apisv1alpha1 "github.com/alexisries/provider-petstore/apis/v1alpha1"
 	petstore "github.com/alexisries/provider-petstore/internal/clients"
 	"github.com/alexisries/provider-petstore/internal/clients/pet"
 	"github.com/alexisries/provider-petstore/internal/clients/pet/fake"
 
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
 	"github.com/crossplane/crossplane-runtime/pkg/meta"
 	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
 	"github.com/crossplane/crossplane-runtime/pkg/resource"
@@ -33,6 +41,9 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 // Unlike many Kubernetes projects Crossplane does not use third party testing
@@ -46,9 +57,33 @@ import (
 var (
 	unexpectedItem resource.Managed
 
-	petIdInt int64 = 565656
-	petIdStr       = strconv.FormatInt(petIdInt, 10)
-	errBoom        = errors.New("Boom")
+	petIdInt   int64 = 565656
+	petIdStr         = strconv.FormatInt(petIdInt, 10)
+	errBoom          = errors.New("Boom")
+	errInvalid       = &petstore.ValidationException{APIError: petstore.APIError{StatusCode: 400, Body: "Invalid ID supplied"}}
+	errServer        = &petstore.ServerException{APIError: petstore.APIError{StatusCode: 502, Body: "Bad Gateway"}}
+	errNoPatch       = &petstore.ValidationException{APIError: petstore.APIError{StatusCode: 405, Body: "Method Not Allowed"}}
+	errDecode        = &petstore.DecodeError{Method: "GET", Path: "/pet/1", ContentType: "text/html", Err: errors.New("invalid JSON")}
+
+	frontImage = v1alpha1.PetImage{
+		Name:               "front.png",
+		AdditionalMetadata: "front",
+		Source: v1alpha1.PetImageSource{
+			ConfigMapKeyRef: &apisv1alpha1.ConfigMapKeySelector{Namespace: "default", Name: "images", Key: "front.png"},
+		},
+	}
+	frontContent = []byte{0x89, 'P', 'N', 'G', 0x00, 0xff}
+	frontHash    = imageHash(frontContent)
+	imagesKube   = &test.MockClient{MockGet: func(_ context.Context, _ client.ObjectKey, obj client.Object) error {
+		switch o := obj.(type) {
+		case *corev1.ConfigMap:
+			o.Data = map[string]string{"front.txt": "front"}
+			o.BinaryData = map[string][]byte{"front.png": frontContent}
+		case *corev1.Secret:
+			o.Data = map[string][]byte{"front.png": frontContent}
+		}
+		return nil
+	}}
 )
 
 type petModifier func(*v1alpha1.Pet)
@@ -65,11 +100,57 @@ func withStatus(status string) petModifier {
 	}
 }
 
-/*
-	func withConditions(c ...xpv1.Condition) petModifier {
-		return func(r *v1alpha1.Pet) { r.Status.ConditionedStatus.Conditions = c }
+func withName(name string) petModifier {
+	return func(r *v1alpha1.Pet) { r.Spec.ForProvider.Name = name }
+}
+
+func withTags(tags ...v1alpha1.PetTag) petModifier {
+	return func(r *v1alpha1.Pet) { r.Spec.ForProvider.Tags = tags }
+}
+
+func withManagedTagIds(ids ...int64) petModifier {
+	return func(r *v1alpha1.Pet) { r.Status.AtProvider.ManagedTagIds = ids }
+}
+
+func withPendingID(id string) petModifier {
+	return func(r *v1alpha1.Pet) {
+		meta.AddAnnotations(r, map[string]string{annotationKeyPendingPetID: id})
 	}
-*/
+}
+
+func withPendingTag(tag string) petModifier {
+	return func(r *v1alpha1.Pet) {
+		meta.AddAnnotations(r, map[string]string{annotationKeyPendingPetTag: tag})
+	}
+}
+
+func withUID(uid types.UID) petModifier {
+	return func(r *v1alpha1.Pet) { r.SetUID(uid) }
+}
+
+func withExternalName(name string) petModifier {
+	return func(r *v1alpha1.Pet) { meta.SetExternalName(r, name) }
+}
+
+func withoutExternalName() petModifier {
+	return func(r *v1alpha1.Pet) { meta.RemoveAnnotations(r, meta.AnnotationKeyExternalName) }
+}
+
+func withImages(images ...v1alpha1.PetImage) petModifier {
+	return func(r *v1alpha1.Pet) { r.Spec.ForProvider.Images = images }
+}
+
+func withUploadedImages(images ...v1alpha1.PetImageObservation) petModifier {
+	return func(r *v1alpha1.Pet) { r.Status.AtProvider.Images = images }
+}
+
+func withConditions(c ...xpv1.Condition) petModifier {
+	return func(r *v1alpha1.Pet) { r.Status.ConditionedStatus.Conditions = c }
+}
+
+func withProviderConfig(name string) petModifier {
+	return func(r *v1alpha1.Pet) { r.SetProviderConfigReference(&xpv1.Reference{Name: name}) }
+}
 
 func newPet(m ...petModifier) *v1alpha1.Pet {
 	pt := &v1alpha1.Pet{}
@@ -80,9 +161,251 @@ func newPet(m ...petModifier) *v1alpha1.Pet {
 	return pt
 }
 
+func TestConnect(t *testing.T) {
+	// The store records the credentials it receives.
+	var sent http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":1,"name":"doggie"}`))
+	}))
+	defer srv.Close()
+
+	secretCreds := apisv1alpha1.ProviderCredentials{
+		Source: xpv1.CredentialsSourceSecret,
+		CommonCredentialSelectors: xpv1.CommonCredentialSelectors{SecretRef: &xpv1.SecretKeySelector{
+			SecretReference: xpv1.SecretReference{Namespace: "crossplane-system", Name: "petstore"},
+			Key:             "credentials",
+		}},
+	}
+	noCreds := apisv1alpha1.ProviderCredentials{Source: xpv1.CredentialsSourceNone}
+	tokenRef := &xpv1.SecretKeySelector{
+		SecretReference: xpv1.SecretReference{Namespace: "crossplane-system", Name: "petstore"},
+		Key:             "token",
+	}
+	caRef := &apisv1alpha1.ConfigMapKeySelector{Namespace: "crossplane-system", Name: "petstore", Key: "ca.crt"}
+	_, errURL := petstore.ParseServerURL("ftp://petstore")
+	connected := []xpv1.Condition{apisv1alpha1.ConfigValid(), apisv1alpha1.StoreAvailable()}
+
+	// openCircuit opens the supplied circuit with a request to a store that is
+	// down.
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	openCircuit := func(t *testing.T, b *petstore.CircuitBreaker) {
+		t.Helper()
+		c := petstore.New(petstore.GetConfig(down.URL,
+			petstore.WithCircuitBreaker(b),
+			petstore.WithRetryPolicy(petstore.RetryPolicy{MaxRetries: -1}),
+		))
+		if _, err := c.DoRequest(context.Background(), "/pet/1", http.MethodGet, nil); err == nil {
+			t.Fatal("a request to a store that is down should fail")
+		}
+		if b.State() != petstore.BreakerOpen {
+			t.Fatalf("the circuit should be open, not %s", b.State())
+		}
+	}
+
+	type want struct {
+		err error
+		// conditions of the managed resource.
+		conditions []xpv1.Condition
+		// pcConditions are the conditions of the ProviderConfig.
+		pcConditions []xpv1.Condition
+		// header sent to the store by the client.
+		header map[string]string
+		// unavailable requests fail fast without reaching the store.
+		unavailable bool
+	}
+
+	cases := map[string]struct {
+		reason     string
+		spec       apisv1alpha1.ProviderConfigSpec
+		secrets    map[string]map[string][]byte
+		configMaps map[string]map[string]string
+		statusErr  error
+		open       bool
+		want       want
+	}{
+		"SecretCredentials": {
+			reason:  "The API key should be read from the Secret of the credentials, trimmed.",
+			spec:    apisv1alpha1.ProviderConfigSpec{Credentials: secretCreds},
+			secrets: map[string]map[string][]byte{"petstore": {"credentials": []byte(" k3y\n")}},
+			want:    want{header: map[string]string{"api_key": "k3y"}, pcConditions: connected},
+		},
+		"NoCredentials": {
+			reason: "No API key should be sent when the credentials source is None.",
+			spec:   apisv1alpha1.ProviderConfigSpec{Credentials: noCreds},
+			want:   want{header: map[string]string{"api_key": ""}, pcConditions: connected},
+		},
+		"CredentialsSecretNotFound": {
+			reason: "A missing credentials Secret should make the credentials unavailable.",
+			spec:   apisv1alpha1.ProviderConfigSpec{Credentials: secretCreds},
+			want: want{
+				err:          errors.Wrap(errors.Wrap(errBoom, "cannot get credentials secret"), errGetCreds),
+				pcConditions: []xpv1.Condition{apisv1alpha1.ConfigValid()},
+				conditions: []xpv1.Condition{v1alpha1.StoreAPIFailed(v1alpha1.ReasonCredentialsUnavailable,
+					errors.Wrap(errors.Wrap(errBoom, "cannot get credentials secret"), errGetCreds))},
+			},
+		},
+		"CredentialsKeyNotFound": {
+			reason:  "A credentials Secret without the selected key should make the credentials unavailable.",
+			spec:    apisv1alpha1.ProviderConfigSpec{Credentials: secretCreds},
+			secrets: map[string]map[string][]byte{"petstore": {"other": []byte("k3y")}},
+			want: want{
+				err:          errors.Wrap(errors.New(errEmptyCreds), errGetCreds),
+				pcConditions: []xpv1.Condition{apisv1alpha1.ConfigValid()},
+				conditions: []xpv1.Condition{v1alpha1.StoreAPIFailed(v1alpha1.ReasonCredentialsUnavailable,
+					errors.Wrap(errors.New(errEmptyCreds), errGetCreds))},
+			},
+		},
+		"HeaderFromSecret": {
+			reason: "The value of a header should be read from its Secret.",
+			spec: apisv1alpha1.ProviderConfigSpec{
+				Credentials: noCreds,
+				Headers:     map[string]apisv1alpha1.HeaderValue{"X-Tenant-Token": {SecretKeyRef: tokenRef}},
+			},
+			secrets: map[string]map[string][]byte{"petstore": {"token": []byte("t0ken\n")}},
+			want:    want{header: map[string]string{"X-Tenant-Token": "t0ken"}, pcConditions: connected},
+		},
+		"HeaderKeyNotFound": {
+			reason:  "A header Secret without the selected key should make the credentials unavailable.",
+			spec:    apisv1alpha1.ProviderConfigSpec{Credentials: noCreds, Headers: map[string]apisv1alpha1.HeaderValue{"X-Tenant-Token": {SecretKeyRef: tokenRef}}},
+			secrets: map[string]map[string][]byte{"petstore": {"credentials": []byte("k3y")}},
+			want: want{
+				err:          errors.Wrapf(errors.Errorf(errFmtKeyNotFound, "token", "Secret", "crossplane-system", "petstore"), errFmtGetHeader, "X-Tenant-Token"),
+				pcConditions: []xpv1.Condition{apisv1alpha1.ConfigValid()},
+				conditions: []xpv1.Condition{v1alpha1.StoreAPIFailed(v1alpha1.ReasonCredentialsUnavailable,
+					errors.Wrapf(errors.Errorf(errFmtKeyNotFound, "token", "Secret", "crossplane-system", "petstore"), errFmtGetHeader, "X-Tenant-Token"))},
+			},
+		},
+		"ConfigMapKeyNotFound": {
+			reason: "A ConfigMap without the selected key should be a TLS error.",
+			spec: apisv1alpha1.ProviderConfigSpec{
+				Credentials: noCreds,
+				TLS:         &apisv1alpha1.TLSConfig{CABundle: &apisv1alpha1.KeySelector{ConfigMapKeyRef: caRef}},
+			},
+			configMaps: map[string]map[string]string{"petstore": {"other.crt": "-----BEGIN CERTIFICATE-----"}},
+			want: want{
+				err:          errors.Wrap(errors.Errorf(errFmtKeyNotFound, "ca.crt", "ConfigMap", "crossplane-system", "petstore"), errGetTLS),
+				pcConditions: []xpv1.Condition{apisv1alpha1.ConfigValid()},
+				conditions: []xpv1.Condition{v1alpha1.StoreAPIFailed(v1alpha1.ReasonTLSError,
+					errors.Wrap(errors.Errorf(errFmtKeyNotFound, "ca.crt", "ConfigMap", "crossplane-system", "petstore"), errGetTLS))},
+			},
+		},
+		"InvalidURL": {
+			reason: "An invalid server URL should make the ProviderConfig invalid.",
+			spec:   apisv1alpha1.ProviderConfigSpec{Credentials: noCreds, ServerUrl: "ftp://petstore"},
+			want: want{
+				err:          errors.Wrap(errURL, errParseURL),
+				conditions:   []xpv1.Condition{v1alpha1.StoreAPIFailed(v1alpha1.ReasonInvalidProviderConfig, errURL)},
+				pcConditions: []xpv1.Condition{apisv1alpha1.ConfigInvalid(apisv1alpha1.ReasonInvalidURL, errURL.Error())},
+			},
+		},
+		"CircuitOpen": {
+			reason: "An open circuit should make the store unavailable, and fail requests fast.",
+			spec:   apisv1alpha1.ProviderConfigSpec{Credentials: noCreds},
+			open:   true,
+			want: want{
+				unavailable: true,
+				pcConditions: []xpv1.Condition{apisv1alpha1.ConfigValid(), apisv1alpha1.StoreUnavailable(apisv1alpha1.ReasonCircuitOpen,
+					"Requests to "+srv.URL+"/v2 are failed fast because it kept failing")},
+			},
+		},
+		"StatusUpdateError": {
+			reason:    "A failure to update the status of the ProviderConfig should not prevent connecting.",
+			spec:      apisv1alpha1.ProviderConfigSpec{Credentials: noCreds},
+			statusErr: errBoom,
+			want:      want{header: map[string]string{"api_key": ""}, pcConditions: connected},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var pcConditions []xpv1.Condition
+			kube := &test.MockClient{
+				MockGet: func(_ context.Context, key client.ObjectKey, obj client.Object) error {
+					switch o := obj.(type) {
+					case *apisv1alpha1.ProviderConfig:
+						o.SetName(key.Name)
+						o.Spec = tc.spec
+						if o.Spec.ServerUrl == "" {
+							o.Spec.ServerUrl = srv.URL + "/v2"
+						}
+					case *corev1.Secret:
+						data, ok := tc.secrets[key.Name]
+						if !ok {
+							return errBoom
+						}
+						o.Data = data
+					case *corev1.ConfigMap:
+						data, ok := tc.configMaps[key.Name]
+						if !ok {
+							return errBoom
+						}
+						o.Data = data
+					}
+					return nil
+				},
+				MockStatusUpdate: func(_ context.Context, obj client.Object, _ ...client.UpdateOption) error {
+					pcConditions = obj.(*apisv1alpha1.ProviderConfig).Status.Conditions
+					return tc.statusErr
+				},
+			}
+			c := &connector{
+				kube:         kube,
+				usage:        resource.TrackerFn(func(_ context.Context, _ resource.Managed) error { return nil }),
+				logger:       logging.NewNopLogger(),
+				recorder:     event.NewNopRecorder(),
+				httpClients:  petstore.NewHTTPClientCache(),
+				tokenSources: petstore.NewTokenSourceCache(),
+				limiters:     petstore.NewLimiterCache(),
+				breakers:     petstore.NewBreakerCache(petstore.BreakerOptions{FailureThreshold: 1}),
+				caches:       petstore.NewResponseCaches(),
+				newServiceFn: pet.NewClient,
+			}
+			if tc.open {
+				openCircuit(t, c.breakers.Get(srv.URL+"/v2"))
+			}
+			cr := newPet(withProviderConfig(name))
+
+			ext, err := c.Connect(context.Background(), cr)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nc.Connect(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(newPet(withProviderConfig(name), withConditions(tc.want.conditions...)), cr, test.EquateConditions()); diff != "" {
+				t.Errorf("\n%s\nc.Connect(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.pcConditions, pcConditions, test.EquateConditions()); diff != "" {
+				t.Errorf("\n%s\nc.Connect(...): ProviderConfig conditions: -want, +got:\n%s\n", tc.reason, diff)
+			}
+			if err != nil {
+				return
+			}
+
+			sent = nil
+			_, err = ext.(*external).service.GetPetById(context.Background(), "1")
+			if tc.want.unavailable {
+				if !petstore.IsErrorStoreUnavailable(err) || sent != nil {
+					t.Errorf("\n%s\nGetPetById(...): want the request to fail fast, got error %v", tc.reason, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("\n%s\nGetPetById(...): %v", tc.reason, err)
+			}
+			for k, v := range tc.want.header {
+				if diff := cmp.Diff(v, sent.Get(k)); diff != "" {
+					t.Errorf("\n%s\nheader %s: -want, +got:\n%s\n", tc.reason, k, diff)
+				}
+			}
+		})
+	}
+}
+
 func TestObserve(t *testing.T) {
 	type args struct {
 		petc pet.Client
+		kube client.Client
 		ctx  context.Context
 		mg   resource.Managed
 	}
@@ -102,7 +425,7 @@ func TestObserve(t *testing.T) {
 		"ValidInput": {
 			args: args{
 				petc: &fake.MockPetClient{
-					MockGetPetById: func(petId string) (*pet.Pet, error) {
+					MockGetPetById: func(_ context.Context, petId string) (*pet.Pet, error) {
 						return &pet.Pet{
 							Id:     &petIdInt,
 							Status: pet.PetStatusAvailable,
@@ -112,7 +435,8 @@ func TestObserve(t *testing.T) {
 				mg: newPet(),
 			},
 			want: want{
-				mg: newPet(withId(petIdInt), withStatus(string(pet.PetStatusAvailable))),
+				mg: newPet(withId(petIdInt), withStatus(string(pet.PetStatusAvailable)),
+					withConditions(v1alpha1.StoreAPISucceeded())),
 				o: managed.ExternalObservation{
 					ResourceExists:   true,
 					ResourceUpToDate: true,
@@ -131,35 +455,277 @@ func TestObserve(t *testing.T) {
 		"ClientError": {
 			args: args{
 				petc: &fake.MockPetClient{
-					MockGetPetById: func(petId string) (*pet.Pet, error) {
+					MockGetPetById: func(_ context.Context, petId string) (*pet.Pet, error) {
 						return nil, errBoom
 					},
 				},
 				mg: newPet(withId(petIdInt)),
 			},
 			want: want{
-				mg:  newPet(withId(petIdInt)),
+				mg:  newPet(withId(petIdInt), withConditions(v1alpha1.StoreAPIFailed(v1alpha1.ReasonRequestFailed, errBoom))),
 				err: errors.Wrap(errBoom, errGetPet),
 			},
 		},
 		"ResourceDoesNotExist": {
 			args: args{
 				petc: &fake.MockPetClient{
-					MockGetPetById: func(petId string) (*pet.Pet, error) {
+					MockGetPetById: func(_ context.Context, petId string) (*pet.Pet, error) {
 						return nil, &petstore.ResourceNotFoundException{}
 					},
 				},
 				mg: newPet(),
 			},
 			want: want{
+				mg: newPet(withConditions(v1alpha1.StoreAPISucceeded())),
+			},
+		},
+		"InvalidSpec": {
+			reason: "A request rejected as invalid should be reported as an invalid spec.",
+			args: args{
+				petc: &fake.MockPetClient{
+					MockGetPetById: func(_ context.Context, petId string) (*pet.Pet, error) {
+						return nil, errInvalid
+					},
+				},
 				mg: newPet(),
 			},
+			want: want{
+				mg:  newPet(withConditions(v1alpha1.StoreAPIFailed(v1alpha1.ReasonInvalidSpec, errInvalid))),
+				err: errors.Wrap(errInvalid, errGetPet),
+			},
+		},
+		"StoreError": {
+			reason: "A server error should be reported as a store error.",
+			args: args{
+				petc: &fake.MockPetClient{
+					MockGetPetById: func(_ context.Context, petId string) (*pet.Pet, error) {
+						return nil, errServer
+					},
+				},
+				mg: newPet(),
+			},
+			want: want{
+				mg:  newPet(withConditions(v1alpha1.StoreAPIFailed(v1alpha1.ReasonStoreError, errServer))),
+				err: errors.Wrap(errServer, errGetPet),
+			},
+		},
+		"InvalidResponse": {
+			reason: "A response that cannot be decoded should be reported as an invalid response.",
+			args: args{
+				petc: &fake.MockPetClient{
+					MockGetPetById: func(_ context.Context, petId string) (*pet.Pet, error) {
+						return nil, errDecode
+					},
+				},
+				mg: newPet(),
+			},
+			want: want{
+				mg:  newPet(withConditions(v1alpha1.StoreAPIFailed(v1alpha1.ReasonInvalidResponse, errDecode))),
+				err: errors.Wrap(errDecode, errGetPet),
+			},
+		},
+		"ImageChanged": {
+			reason: "A pet whose image changed since it was uploaded should not be up to date.",
+			args: args{
+				petc: &fake.MockPetClient{
+					MockGetPetById: func(_ context.Context, petId string) (*pet.Pet, error) {
+						return &pet.Pet{Id: &petIdInt}, nil
+					},
+				},
+				kube: imagesKube,
+				mg: newPet(withImages(frontImage),
+					withUploadedImages(v1alpha1.PetImageObservation{Name: "front.png", SHA256: "old", AdditionalMetadata: "front"})),
+			},
+			want: want{
+				mg: newPet(withImages(frontImage), withId(petIdInt),
+					withUploadedImages(v1alpha1.PetImageObservation{Name: "front.png", SHA256: "old", AdditionalMetadata: "front"}),
+					withConditions(v1alpha1.StoreAPISucceeded())),
+				o: managed.ExternalObservation{ResourceExists: true},
+			},
+		},
+		"ImageUnchanged": {
+			reason: "A pet whose images were uploaded as they are should be up to date.",
+			args: args{
+				petc: &fake.MockPetClient{
+					MockGetPetById: func(_ context.Context, petId string) (*pet.Pet, error) {
+						return &pet.Pet{Id: &petIdInt}, nil
+					},
+				},
+				kube: imagesKube,
+				mg: newPet(withImages(frontImage),
+					withUploadedImages(v1alpha1.PetImageObservation{Name: "front.png", SHA256: frontHash, AdditionalMetadata: "front"})),
+			},
+			want: want{
+				mg: newPet(withImages(frontImage), withId(petIdInt),
+					withUploadedImages(v1alpha1.PetImageObservation{Name: "front.png", SHA256: frontHash, AdditionalMetadata: "front"}),
+					withConditions(v1alpha1.StoreAPISucceeded())),
+				o: managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true},
+			},
+		},
+		"TagDropped": {
+			reason: "A pet still having a tag dropped from the spec should not be up to date, unlike one having a tag someone else added.",
+			args: args{
+				petc: &fake.MockPetClient{
+					MockGetPetById: func(_ context.Context, petId string) (*pet.Pet, error) {
+						return &pet.Pet{Id: &petIdInt, Tags: &[]pet.Tag{
+							{Id: petstore.Int64(1), Name: petstore.String("friendly")},
+							{Id: petstore.Int64(2), Name: petstore.String("vip")},
+						}}, nil
+					},
+				},
+				mg: newPet(withManagedTagIds(1)),
+			},
+			want: want{
+				mg: newPet(withId(petIdInt), withManagedTagIds(1), withConditions(v1alpha1.StoreAPISucceeded())),
+				o:  managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: false},
+			},
+		},
+		"NotCreated": {
+			reason: "A pet without external name or pending creation should not exist.",
+			args: args{
+				mg: newPet(withoutExternalName()),
+			},
+			want: want{
+				mg: newPet(withoutExternalName()),
+			},
+		},
+		"PendingCreationSucceeded": {
+			reason: "A pet whose pending creation succeeded should be adopted rather than created again.",
+			args: args{
+				petc: &fake.MockPetClient{
+					MockGetPetById: func(_ context.Context, petId string) (*pet.Pet, error) {
+						if petId != petIdStr {
+							return nil, &petstore.ResourceNotFoundException{}
+						}
+						return &pet.Pet{Id: &petIdInt, Name: "doggie", Status: pet.PetStatusAvailable}, nil
+					},
+				},
+				mg: newPet(withoutExternalName(), withName("doggie"), withPendingID(petIdStr)),
+			},
+			want: want{
+				mg: newPet(withName("doggie"), withId(petIdInt), withStatus(string(pet.PetStatusAvailable)),
+					withConditions(v1alpha1.StoreAPISucceeded())),
+				o: managed.ExternalObservation{
+					ResourceExists:          true,
+					ResourceUpToDate:        true,
+					ResourceLateInitialized: true,
+				},
+			},
+		},
+		"PendingCreationFailed": {
+			reason: "A pet whose pending creation did not succeed should be created again with the same ID.",
+			args: args{
+				petc: &fake.MockPetClient{
+					MockGetPetById: func(_ context.Context, petId string) (*pet.Pet, error) {
+						return nil, &petstore.ResourceNotFoundException{}
+					},
+				},
+				mg: newPet(withoutExternalName(), withPendingID(petIdStr)),
+			},
+			want: want{
+				mg: newPet(withoutExternalName(), withPendingID(petIdStr), withConditions(v1alpha1.StoreAPISucceeded())),
+			},
+		},
+		"PendingIDTaken": {
+			reason: "A pending ID used by another pet should be dropped, so that a new one is picked.",
+			args: args{
+				petc: &fake.MockPetClient{
+					MockGetPetById: func(_ context.Context, petId string) (*pet.Pet, error) {
+						return &pet.Pet{Id: &petIdInt, Name: "kitty"}, nil
+					},
+				},
+				mg: newPet(withoutExternalName(), withName("doggie"), withPendingID(petIdStr)),
+			},
+			want: want{
+				mg: newPet(withoutExternalName(), withName("doggie"), withConditions(v1alpha1.StoreAPISucceeded())),
+			},
+		},
+		"PendingServerCreationSucceeded": {
+			reason: "A pet carrying the marker tag of a pending creation should be adopted rather than created again, and updated to remove the marker.",
+			args: args{
+				petc: &fake.MockPetClient{
+					MockFindPetsByTags: func(_ context.Context, tags []string, visit func(*pet.Pet) error) error {
+						if len(tags) != 1 || tags[0] != "crossplane-pet-uid" {
+							return errBoom
+						}
+						for _, p := range []*pet.Pet{
+							{Id: petstore.Int64(1), Name: "kitty", Tags: &[]pet.Tag{{Name: petstore.String("crossplane-pet-uid")}}},
+							{Id: petstore.Int64(2), Name: "doggie", Tags: &[]pet.Tag{{Name: petstore.String("other")}}},
+							{Id: &petIdInt, Name: "doggie", Tags: &[]pet.Tag{{Name: petstore.String("crossplane-pet-uid")}}},
+						} {
+							if err := visit(p); err != nil {
+								return err
+							}
+						}
+						return nil
+					},
+				},
+				mg: newPet(withoutExternalName(), withUID("pet-uid"), withName("doggie"), withPendingTag("crossplane-pet-uid")),
+			},
+			want: want{
+				mg: newPet(withUID("pet-uid"), withName("doggie"), withId(petIdInt), withConditions(v1alpha1.StoreAPISucceeded())),
+				o: managed.ExternalObservation{
+					ResourceExists:          true,
+					ResourceUpToDate:        false,
+					ResourceLateInitialized: true,
+				},
+			},
+		},
+		"PendingServerCreationFailed": {
+			reason: "A pet whose pending creation did not succeed should be created again with the same marker tag.",
+			args: args{
+				petc: &fake.MockPetClient{
+					MockFindPetsByTags: func(_ context.Context, _ []string, _ func(*pet.Pet) error) error {
+						return nil
+					},
+				},
+				mg: newPet(withoutExternalName(), withPendingTag("crossplane-pet-uid")),
+			},
+			want: want{
+				mg: newPet(withoutExternalName(), withPendingTag("crossplane-pet-uid"),
+					withConditions(v1alpha1.StoreAPISucceeded())),
+			},
+		},
+		"PendingServerCreationError": {
+			reason: "A failure to look for a pet whose creation is pending should be returned.",
+			args: args{
+				petc: &fake.MockPetClient{
+					MockFindPetsByTags: func(_ context.Context, _ []string, _ func(*pet.Pet) error) error {
+						return errBoom
+					},
+				},
+				mg: newPet(withoutExternalName(), withPendingTag("crossplane-pet-uid")),
+			},
+			want: want{
+				mg: newPet(withoutExternalName(), withPendingTag("crossplane-pet-uid"),
+					withConditions(v1alpha1.StoreAPIFailed(v1alpha1.ReasonRequestFailed, errBoom))),
+				err: errors.Wrap(errBoom, errGetPendingPet),
+			},
+		},
+		"PendingCreationConfirmed": {
+			reason: "The pending creation marker should be removed once the external name is set.",
+			args: args{
+				petc: &fake.MockPetClient{
+					MockGetPetById: func(_ context.Context, petId string) (*pet.Pet, error) {
+						return &pet.Pet{Id: &petIdInt}, nil
+					},
+				},
+				mg: newPet(withPendingID(petIdStr)),
+			},
+			want: want{
+				mg: newPet(withId(petIdInt), withConditions(v1alpha1.StoreAPISucceeded())),
+				o: managed.ExternalObservation{
+					ResourceExists:          true,
+					ResourceUpToDate:        true,
+					ResourceLateInitialized: true,
+				},
+			},
 		},
 	}
 
 	for name, tc := range cases {
 		t.Run(name, func(t *testing.T) {
-			e := external{service: tc.args.petc}
+			e := external{service: tc.args.petc, kube: tc.args.kube, imageSourceNamespaces: []string{"default"}}
 			got, err := e.Observe(tc.args.ctx, tc.args.mg)
 			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
 				t.Errorf("\n%s\ne.Observe(...): -want error, +got error:\n%s\n", tc.reason, diff)
@@ -176,9 +742,12 @@ func TestObserve(t *testing.T) {
 
 func TestCreate(t *testing.T) {
 	type args struct {
-		petc pet.Client
-		ctx  context.Context
-		mg   resource.Managed
+		petc     pet.Client
+		kube     client.Client
+		strategy pet.IDStrategy
+		ids      []int64
+		ctx      context.Context
+		mg       resource.Managed
 	}
 
 	type want struct {
@@ -187,29 +756,195 @@ func TestCreate(t *testing.T) {
 		mg  resource.Managed
 	}
 
+	notFound := func(_ context.Context, petId string) (*pet.Pet, error) {
+		return nil, &petstore.ResourceNotFoundException{}
+	}
+
 	cases := map[string]struct {
 		reason string
 		args   args
 		want   want
 		err    error
 	}{
-		"ValidInput": {
+		"ServerAssignedID": {
+			reason: "The ID assigned by the store should become the external name.",
 			args: args{
 				petc: &fake.MockPetClient{
-					MockAddPet: func(petInput *pet.Pet) (*pet.Pet, error) {
+					MockAddPet: func(_ context.Context, petInput *pet.Pet, idempotencyKey string) (*pet.Pet, error) {
+						if petInput.Id != nil || idempotencyKey != "pet-uid" || !hasTag(petInput, "crossplane-pet-uid") {
+							return nil, errBoom
+						}
 						return &pet.Pet{
 							Id:     &petIdInt,
 							Status: pet.PetStatusPending,
 						}, nil
 					},
 				},
-				mg: newPet(),
+				kube: &test.MockClient{MockUpdate: test.NewMockUpdateFn(nil)},
+				mg:   newPet(withoutExternalName(), withUID("pet-uid")),
 			},
 			want: want{
-				mg: newPet(),
+				mg: newPet(withUID("pet-uid"), withPendingTag("crossplane-pet-uid"), withId(petIdInt),
+					withStatus(string(pet.PetStatusPending)), withConditions(v1alpha1.StoreAPISucceeded())),
+				o: managed.ExternalCreation{},
+			},
+		},
+		"RetryPendingServerCreation": {
+			reason: "A retried creation should reuse the pending marker tag.",
+			args: args{
+				petc: &fake.MockPetClient{
+					MockAddPet: func(_ context.Context, petInput *pet.Pet, _ string) (*pet.Pet, error) {
+						if petInput.Tags == nil || len(*petInput.Tags) != 1 || !hasTag(petInput, "crossplane-pet-uid") {
+							return nil, errBoom
+						}
+						return &pet.Pet{Id: &petIdInt}, nil
+					},
+				},
+				mg: newPet(withoutExternalName(), withUID("pet-uid"), withPendingTag("crossplane-pet-uid")),
+			},
+			want: want{
+				mg: newPet(withUID("pet-uid"), withPendingTag("crossplane-pet-uid"), withId(petIdInt),
+					withConditions(v1alpha1.StoreAPISucceeded())),
+				o: managed.ExternalCreation{},
+			},
+		},
+		"RecordPendingTagError": {
+			reason: "No pet should be created if its marker tag cannot be recorded.",
+			args: args{
+				kube: &test.MockClient{MockUpdate: test.NewMockUpdateFn(errBoom)},
+				mg:   newPet(withoutExternalName(), withUID("pet-uid")),
+			},
+			want: want{
+				mg:  newPet(withoutExternalName(), withUID("pet-uid"), withPendingTag("crossplane-pet-uid")),
+				err: errors.Wrap(errBoom, errRecordPending),
+			},
+		},
+		"ClientAssignedID": {
+			reason: "A free ID picked by the client should be recorded before the pet is created with it.",
+			args: args{
+				petc: &fake.MockPetClient{
+					MockGetPetById: notFound,
+					MockAddPet: func(_ context.Context, petInput *pet.Pet, idempotencyKey string) (*pet.Pet, error) {
+						if *petInput.Id != petIdInt || idempotencyKey != "pet-uid-"+petIdStr {
+							return nil, errBoom
+						}
+						return petInput, nil
+					},
+				},
+				kube:     &test.MockClient{MockUpdate: test.NewMockUpdateFn(nil)},
+				strategy: pet.IDStrategyClient,
+				ids:      []int64{petIdInt},
+				mg:       newPet(withoutExternalName(), withUID("pet-uid")),
+			},
+			want: want{
+				mg: newPet(withUID("pet-uid"), withPendingID(petIdStr), withId(petIdInt),
+					withConditions(v1alpha1.StoreAPISucceeded())),
+				o: managed.ExternalCreation{},
+			},
+		},
+		"ClientAssignedIDCollision": {
+			reason: "An ID some pet already has should not be picked.",
+			args: args{
+				petc: &fake.MockPetClient{
+					MockGetPetById: func(_ context.Context, petId string) (*pet.Pet, error) {
+						if petId == "42" {
+							return &pet.Pet{Id: petstore.Int64(42)}, nil
+						}
+						return nil, &petstore.ResourceNotFoundException{}
+					},
+					MockAddPet: func(_ context.Context, petInput *pet.Pet, _ string) (*pet.Pet, error) {
+						return petInput, nil
+					},
+				},
+				kube:     &test.MockClient{MockUpdate: test.NewMockUpdateFn(nil)},
+				strategy: pet.IDStrategyClient,
+				ids:      []int64{42, petIdInt},
+				mg:       newPet(withoutExternalName()),
+			},
+			want: want{
+				mg: newPet(withPendingID(petIdStr), withId(petIdInt), withConditions(v1alpha1.StoreAPISucceeded())),
 				o:  managed.ExternalCreation{},
 			},
 		},
+		"NoFreeID": {
+			reason: "Creation should fail rather than pick an ID some pet already has.",
+			args: args{
+				petc: &fake.MockPetClient{
+					MockGetPetById: func(_ context.Context, petId string) (*pet.Pet, error) {
+						return &pet.Pet{Id: &petIdInt}, nil
+					},
+				},
+				strategy: pet.IDStrategyClient,
+				ids:      []int64{petIdInt, petIdInt, petIdInt, petIdInt, petIdInt},
+				mg:       newPet(withoutExternalName()),
+			},
+			want: want{
+				mg:  newPet(withoutExternalName()),
+				err: errors.Wrap(errors.Errorf(errFmtNoFreePetID, maxPetIDAttempts), errNewPetID),
+			},
+		},
+		"RandomError": {
+			reason: "A failure to generate a random ID should be returned rather than panic.",
+			args: args{
+				strategy: pet.IDStrategyClient,
+				mg:       newPet(withoutExternalName()),
+			},
+			want: want{
+				mg:  newPet(withoutExternalName()),
+				err: errors.Wrap(errBoom, errNewPetID),
+			},
+		},
+		"RetryPendingCreation": {
+			reason: "A retried creation should reuse the pending ID and Idempotency-Key.",
+			args: args{
+				petc: &fake.MockPetClient{
+					MockAddPet: func(_ context.Context, petInput *pet.Pet, idempotencyKey string) (*pet.Pet, error) {
+						if *petInput.Id != 42 || idempotencyKey != "pet-uid-42" {
+							return nil, errBoom
+						}
+						return petInput, nil
+					},
+				},
+				strategy: pet.IDStrategyClient,
+				mg:       newPet(withoutExternalName(), withUID("pet-uid"), withPendingID("42")),
+			},
+			want: want{
+				mg: newPet(withUID("pet-uid"), withPendingID("42"), withExternalName("42"), withId(42),
+					withConditions(v1alpha1.StoreAPISucceeded())),
+				o: managed.ExternalCreation{},
+			},
+		},
+		"RecordPendingError": {
+			reason: "No pet should be created if its pending creation cannot be recorded.",
+			args: args{
+				petc:     &fake.MockPetClient{MockGetPetById: notFound},
+				kube:     &test.MockClient{MockUpdate: test.NewMockUpdateFn(errBoom)},
+				strategy: pet.IDStrategyClient,
+				ids:      []int64{petIdInt},
+				mg:       newPet(withoutExternalName()),
+			},
+			want: want{
+				mg:  newPet(withoutExternalName(), withPendingID(petIdStr)),
+				err: errors.Wrap(errBoom, errRecordPending),
+			},
+		},
+		"NoPetReturned": {
+			reason: "A creation returning no pet should be an error.",
+			args: args{
+				petc: &fake.MockPetClient{
+					MockAddPet: func(_ context.Context, petInput *pet.Pet, idempotencyKey string) (*pet.Pet, error) {
+						return nil, nil
+					},
+				},
+				kube: &test.MockClient{MockUpdate: test.NewMockUpdateFn(nil)},
+				mg:   newPet(withoutExternalName()),
+			},
+			want: want{
+				mg: newPet(withoutExternalName(), withPendingTag(pendingPetTagPrefix),
+					withConditions(v1alpha1.StoreAPISucceeded())),
+				err: errors.New(errSDK),
+			},
+		},
 		"InValidInput": {
 			args: args{
 				mg: unexpectedItem,
@@ -222,21 +957,36 @@ func TestCreate(t *testing.T) {
 		"ClientError": {
 			args: args{
 				petc: &fake.MockPetClient{
-					MockAddPet: func(petInput *pet.Pet) (*pet.Pet, error) {
+					MockAddPet: func(_ context.Context, petInput *pet.Pet, idempotencyKey string) (*pet.Pet, error) {
 						return nil, errBoom
 					},
 				},
-				mg: newPet(),
+				kube: &test.MockClient{MockUpdate: test.NewMockUpdateFn(nil)},
+				mg:   newPet(),
 			},
 			want: want{
-				mg:  newPet(),
+				mg: newPet(withPendingTag(pendingPetTagPrefix),
+					withConditions(v1alpha1.StoreAPIFailed(v1alpha1.ReasonRequestFailed, errBoom))),
 				err: errors.Wrap(errBoom, errCreatePet),
 			},
 		},
 	}
 	for name, tc := range cases {
 		t.Run(name, func(t *testing.T) {
-			e := external{service: tc.args.petc}
+			ids := tc.args.ids
+			e := external{
+				service:    tc.args.petc,
+				kube:       tc.args.kube,
+				idStrategy: tc.args.strategy,
+				newPetID: func() (int64, error) {
+					if len(ids) == 0 {
+						return 0, errBoom
+					}
+					id := ids[0]
+					ids = ids[1:]
+					return id, nil
+				},
+			}
 			got, err := e.Create(tc.args.ctx, tc.args.mg)
 			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
 				t.Errorf("\n%s\ne.Observe(...): -want error, +got error:\n%s\n", tc.reason, diff)
@@ -251,11 +1001,55 @@ func TestCreate(t *testing.T) {
 	}
 }
 
+func TestCreateResponseLost(t *testing.T) {
+	// The store creates the pet, but its response never reaches the provider.
+	var created []*pet.Pet
+	svc := &fake.MockPetClient{
+		MockAddPet: func(_ context.Context, p *pet.Pet, _ string) (*pet.Pet, error) {
+			stored := *p
+			stored.Id = petstore.Int64(int64(len(created) + 1))
+			created = append(created, &stored)
+			return nil, errBoom
+		},
+		MockFindPetsByTags: func(_ context.Context, tags []string, visit func(*pet.Pet) error) error {
+			for _, p := range created {
+				if hasTag(p, tags[0]) {
+					if err := visit(p); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	}
+	e := external{service: svc, kube: &test.MockClient{MockUpdate: test.NewMockUpdateFn(nil)}, idStrategy: pet.IDStrategyServer}
+	cr := newPet(withoutExternalName(), withUID("pet-uid"), withName("doggie"))
+
+	if _, err := e.Create(context.Background(), cr); !errors.Is(err, errBoom) {
+		t.Fatalf("e.Create(...): want error %v, got %v", errBoom, err)
+	}
+	o, err := e.Observe(context.Background(), cr)
+	if err != nil {
+		t.Fatalf("e.Observe(...): %v", err)
+	}
+	if !o.ResourceExists {
+		t.Errorf("e.Observe(...): the pet created by the lost request should exist")
+	}
+	if got := meta.GetExternalName(cr); got != "1" {
+		t.Errorf("e.Observe(...): want external name %q, got %q", "1", got)
+	}
+	if len(created) != 1 {
+		t.Errorf("e.Create(...): want 1 pet created, got %d", len(created))
+	}
+}
+
 func TestUpdate(t *testing.T) {
 	type args struct {
-		petc pet.Client
-		ctx  context.Context
-		mg   resource.Managed
+		petc    pet.Client
+		kube    client.Client
+		patches *pet.PatchSupport
+		ctx     context.Context
+		mg      resource.Managed
 	}
 
 	type want struct {
@@ -264,6 +1058,27 @@ func TestUpdate(t *testing.T) {
 		mg  resource.Managed
 	}
 
+	const server = "https://petstore.example.com/v2"
+	friendly := v1alpha1.PetTag{Id: 1, Name: "friendly"}
+	// The pet lacks the managed tag, and has a tag someone else added.
+	drifted := func(_ context.Context, petId string) (*pet.Pet, error) {
+		return &pet.Pet{Id: &petIdInt, Name: "doggie", Status: pet.PetStatusAvailable,
+			Tags: &[]pet.Tag{{Id: petstore.Int64(2), Name: petstore.String("vip")}}}, nil
+	}
+	tags := &[]pet.Tag{
+		{Id: petstore.Int64(2), Name: petstore.String("vip")},
+		{Id: petstore.Int64(1), Name: petstore.String("friendly")},
+	}
+	patched := &pet.Pet{Id: &petIdInt, Name: "doggie", Status: pet.PetStatusAvailable, Tags: tags}
+	unsupported := func() *pet.PatchSupport {
+		s := pet.NewPatchSupport()
+		s.SetUnsupported(server)
+		return s
+	}
+	upToDate := func(_ context.Context, petId string) (*pet.Pet, error) {
+		return &pet.Pet{Id: &petIdInt}, nil
+	}
+
 	cases := map[string]struct {
 		reason string
 		args   args
@@ -271,17 +1086,205 @@ func TestUpdate(t *testing.T) {
 		err    error
 	}{
 		"ValidInput": {
+			reason: "A pet whose fields other than its name drifted should be patched with the drifted fields, keeping tags added by others.",
+			args: args{
+				petc: &fake.MockPetClient{
+					MockGetPetByIdUncached: drifted,
+					MockPatchPet: func(_ context.Context, petId string, patch pet.PetPatch) (*pet.Pet, error) {
+						if diff := cmp.Diff(pet.PetPatch{Tags: tags}, patch); petId != petIdStr || diff != "" {
+							return nil, errors.Errorf("unexpected patch: %s", diff)
+						}
+						return patched, nil
+					},
+				},
+				mg: newPet(withName("doggie"), withTags(friendly)),
+			},
+			want: want{
+				mg: newPet(withName("doggie"), withTags(friendly), withId(petIdInt), withStatus(string(pet.PetStatusAvailable)),
+					withManagedTagIds(1), withConditions(v1alpha1.StoreAPISucceeded())),
+				o: managed.ExternalUpdate{},
+			},
+		},
+		"TagDropped": {
+			reason: "A tag dropped from the spec should be removed from the pet, keeping tags added by others.",
+			args: args{
+				petc: &fake.MockPetClient{
+					MockGetPetByIdUncached: func(_ context.Context, petId string) (*pet.Pet, error) {
+						return patched, nil
+					},
+					MockPatchPet: func(_ context.Context, petId string, patch pet.PetPatch) (*pet.Pet, error) {
+						want := pet.PetPatch{Tags: &[]pet.Tag{{Id: petstore.Int64(2), Name: petstore.String("vip")}}}
+						if diff := cmp.Diff(want, patch); diff != "" {
+							return nil, errors.Errorf("unexpected patch: %s", diff)
+						}
+						return patch.Apply(patched), nil
+					},
+				},
+				mg: newPet(withName("doggie"), withManagedTagIds(1)),
+			},
+			want: want{
+				mg: newPet(withName("doggie"), withId(petIdInt), withStatus(string(pet.PetStatusAvailable)),
+					withConditions(v1alpha1.StoreAPISucceeded())),
+			},
+		},
+		"MarkerTagRemoved": {
+			reason: "The marker tag of the creation of a pet should be removed from it, keeping tags added by others.",
+			args: args{
+				petc: &fake.MockPetClient{
+					MockGetPetByIdUncached: func(_ context.Context, petId string) (*pet.Pet, error) {
+						return &pet.Pet{Id: &petIdInt, Name: "doggie", Status: pet.PetStatusAvailable, Tags: &[]pet.Tag{
+							{Id: petstore.Int64(2), Name: petstore.String("vip")},
+							{Name: petstore.String("crossplane-pet-uid")},
+						}}, nil
+					},
+					MockPatchPet: func(_ context.Context, petId string, patch pet.PetPatch) (*pet.Pet, error) {
+						want := pet.PetPatch{Tags: &[]pet.Tag{{Id: petstore.Int64(2), Name: petstore.String("vip")}}}
+						if diff := cmp.Diff(want, patch); diff != "" {
+							return nil, errors.Errorf("unexpected patch: %s", diff)
+						}
+						return patch.Apply(patched), nil
+					},
+				},
+				mg: newPet(withUID("pet-uid"), withName("doggie")),
+			},
+			want: want{
+				mg: newPet(withUID("pet-uid"), withName("doggie"), withId(petIdInt), withStatus(string(pet.PetStatusAvailable)),
+					withConditions(v1alpha1.StoreAPISucceeded())),
+			},
+		},
+		"PatchUnsupported": {
+			reason: "A pet in a store that does not support patches should be read, modified and put back, keeping the fields not managed.",
+			args: args{
+				petc: &fake.MockPetClient{
+					MockGetPetByIdUncached: drifted,
+					MockPatchPet: func(_ context.Context, petId string, patch pet.PetPatch) (*pet.Pet, error) {
+						return nil, errNoPatch
+					},
+					MockUpdatePetById: func(_ context.Context, petId string, petInput *pet.Pet) (*pet.Pet, error) {
+						if diff := cmp.Diff(patched, petInput); petId != petIdStr || diff != "" {
+							return nil, errors.Errorf("unexpected pet: %s", diff)
+						}
+						return petInput, nil
+					},
+				},
+				patches: pet.NewPatchSupport(),
+				mg:      newPet(withName("doggie"), withTags(friendly)),
+			},
+			want: want{
+				mg: newPet(withName("doggie"), withTags(friendly), withId(petIdInt), withStatus(string(pet.PetStatusAvailable)),
+					withManagedTagIds(1), withConditions(v1alpha1.StoreAPISucceeded())),
+			},
+		},
+		"PatchKnownUnsupported": {
+			reason: "A pet in a store known not to support patches should be put back without trying to patch it.",
+			args: args{
+				petc: &fake.MockPetClient{
+					MockGetPetByIdUncached: drifted,
+					MockUpdatePetById: func(_ context.Context, petId string, petInput *pet.Pet) (*pet.Pet, error) {
+						return petInput, nil
+					},
+				},
+				patches: unsupported(),
+				mg:      newPet(withName("doggie"), withTags(friendly)),
+			},
+			want: want{
+				mg: newPet(withName("doggie"), withTags(friendly), withId(petIdInt), withStatus(string(pet.PetStatusAvailable)),
+					withManagedTagIds(1), withConditions(v1alpha1.StoreAPISucceeded())),
+			},
+		},
+		"UploadImages": {
+			reason: "Changed images should be uploaded, and images no longer in the spec forgotten, without updating the pet.",
 			args: args{
 				petc: &fake.MockPetClient{
-					MockUpdatePetById: func(petId string, petInput *pet.Pet) error {
+					MockGetPetByIdUncached: upToDate,
+					MockUploadImage: func(_ context.Context, petId string, fileName string, image []byte, additionalMetadata string) (*pet.ApiResponse, error) {
+						if petId != petIdStr || fileName != "front.png" || string(image) != string(frontContent) || additionalMetadata != "front" {
+							return nil, errBoom
+						}
+						return &pet.ApiResponse{Code: 200}, nil
+					},
+				},
+				kube: imagesKube,
+				mg: newPet(withImages(frontImage), withUploadedImages(
+					v1alpha1.PetImageObservation{Name: "front.png", SHA256: "old"},
+					v1alpha1.PetImageObservation{Name: "back.png", SHA256: "old"})),
+			},
+			want: want{
+				mg: newPet(withImages(frontImage), withId(petIdInt),
+					withUploadedImages(v1alpha1.PetImageObservation{Name: "front.png", SHA256: frontHash, AdditionalMetadata: "front"}),
+					withConditions(v1alpha1.StoreAPISucceeded())),
+			},
+		},
+		"UploadImageError": {
+			reason: "A failed upload should be returned, and the image not recorded as uploaded.",
+			args: args{
+				petc: &fake.MockPetClient{
+					MockGetPetByIdUncached: upToDate,
+					MockUploadImage: func(_ context.Context, petId string, fileName string, image []byte, additionalMetadata string) (*pet.ApiResponse, error) {
+						return nil, errBoom
+					},
+				},
+				kube: imagesKube,
+				mg:   newPet(withImages(frontImage)),
+			},
+			want: want{
+				mg: newPet(withImages(frontImage), withId(petIdInt),
+					withConditions(v1alpha1.StoreAPIFailed(v1alpha1.ReasonRequestFailed, errBoom))),
+				err: errors.Wrapf(errBoom, errFmtUploadImage, "front.png"),
+			},
+		},
+		"Rename": {
+			reason: "A pet whose name alone drifted should be renamed with a form.",
+			args: args{
+				petc: &fake.MockPetClient{
+					MockGetPetByIdUncached: func(_ context.Context, petId string) (*pet.Pet, error) {
+						return &pet.Pet{Id: &petIdInt, Name: "kitty", Status: pet.PetStatusAvailable}, nil
+					},
+					MockUpdatePetWithForm: func(_ context.Context, petId string, name string, status pet.PetStatus) error {
+						if petId != petIdStr || name != "doggie" || status != "" {
+							return errBoom
+						}
 						return nil
 					},
 				},
-				mg: newPet(),
+				mg: newPet(withName("doggie")),
+			},
+			want: want{
+				mg: newPet(withName("doggie"), withId(petIdInt), withStatus(string(pet.PetStatusAvailable)),
+					withConditions(v1alpha1.StoreAPISucceeded())),
+			},
+		},
+		"RenameError": {
+			reason: "A failed rename should be returned.",
+			args: args{
+				petc: &fake.MockPetClient{
+					MockGetPetByIdUncached: func(_ context.Context, petId string) (*pet.Pet, error) {
+						return &pet.Pet{Id: &petIdInt, Name: "kitty"}, nil
+					},
+					MockUpdatePetWithForm: func(_ context.Context, petId string, name string, status pet.PetStatus) error {
+						return errBoom
+					},
+				},
+				mg: newPet(withName("doggie")),
 			},
 			want: want{
+				mg:  newPet(withName("doggie"), withConditions(v1alpha1.StoreAPIFailed(v1alpha1.ReasonRequestFailed, errBoom))),
+				err: errors.Wrap(errBoom, errUpdatePet),
+			},
+		},
+		"GetError": {
+			reason: "A pet that cannot be read should not be updated.",
+			args: args{
+				petc: &fake.MockPetClient{
+					MockGetPetByIdUncached: func(_ context.Context, petId string) (*pet.Pet, error) {
+						return nil, errBoom
+					},
+				},
 				mg: newPet(),
-				o:  managed.ExternalUpdate{},
+			},
+			want: want{
+				mg:  newPet(withConditions(v1alpha1.StoreAPIFailed(v1alpha1.ReasonRequestFailed, errBoom))),
+				err: errors.Wrap(errBoom, errGetPet),
 			},
 		},
 		"InValidInput": {
@@ -296,14 +1299,15 @@ func TestUpdate(t *testing.T) {
 		"ClientError": {
 			args: args{
 				petc: &fake.MockPetClient{
-					MockUpdatePetById: func(petId string, petInput *pet.Pet) error {
-						return errBoom
+					MockGetPetByIdUncached: drifted,
+					MockPatchPet: func(_ context.Context, petId string, patch pet.PetPatch) (*pet.Pet, error) {
+						return nil, errBoom
 					},
 				},
-				mg: newPet(withId(petIdInt)),
+				mg: newPet(withId(petIdInt), withTags(friendly)),
 			},
 			want: want{
-				mg:  newPet(withId(petIdInt)),
+				mg:  newPet(withId(petIdInt), withTags(friendly), withConditions(v1alpha1.StoreAPIFailed(v1alpha1.ReasonRequestFailed, errBoom))),
 				err: errors.Wrap(errBoom, errUpdatePet),
 			},
 		},
@@ -311,7 +1315,8 @@ func TestUpdate(t *testing.T) {
 
 	for name, tc := range cases {
 		t.Run(name, func(t *testing.T) {
-			e := external{service: tc.args.petc}
+			e := external{service: tc.args.petc, kube: tc.args.kube, patches: tc.args.patches, server: server,
+				imageSourceNamespaces: []string{"default"}}
 			got, err := e.Update(tc.args.ctx, tc.args.mg)
 			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
 				t.Errorf("\n%s\ne.Observe(...): -want error, +got error:\n%s\n", tc.reason, diff)
@@ -322,6 +1327,9 @@ func TestUpdate(t *testing.T) {
 			if diff := cmp.Diff(tc.want.o, got); diff != "" {
 				t.Errorf("\n%s\ne.Observe(...): -want, +got:\n%s\n", tc.reason, diff)
 			}
+			if tc.args.patches != nil && tc.args.patches.Supported(server) {
+				t.Errorf("\n%s\ne.Update(...): store should be known not to support patches", tc.reason)
+			}
 		})
 	}
 }
@@ -348,14 +1356,14 @@ func TestDelete(t *testing.T) {
 		"ValidInput": {
 			args: args{
 				petc: &fake.MockPetClient{
-					MockDeletePetById: func(petId string) error {
+					MockDeletePetById: func(_ context.Context, petId string) error {
 						return nil
 					},
 				},
 				mg: newPet(),
 			},
 			want: want{
-				mg: newPet(),
+				mg: newPet(withConditions(v1alpha1.StoreAPISucceeded())),
 				o:  managed.ExternalUpdate{},
 			},
 		},
@@ -371,28 +1379,28 @@ func TestDelete(t *testing.T) {
 		"ClientError": {
 			args: args{
 				petc: &fake.MockPetClient{
-					MockDeletePetById: func(petId string) error {
+					MockDeletePetById: func(_ context.Context, petId string) error {
 						return errBoom
 					},
 				},
 				mg: newPet(withId(petIdInt)),
 			},
 			want: want{
-				mg:  newPet(withId(petIdInt)),
+				mg:  newPet(withId(petIdInt), withConditions(v1alpha1.StoreAPIFailed(v1alpha1.ReasonRequestFailed, errBoom))),
 				err: errors.Wrap(errBoom, errDeletePet),
 			},
 		},
 		"ResourceDoesNotExist": {
 			args: args{
 				petc: &fake.MockPetClient{
-					MockDeletePetById: func(petId string) error {
+					MockDeletePetById: func(_ context.Context, petId string) error {
 						return &petstore.ResourceNotFoundException{}
 					},
 				},
 				mg: newPet(),
 			},
 			want: want{
-				mg: newPet(),
+				mg: newPet(withConditions(v1alpha1.StoreAPISucceeded())),
 			},
 		},
 	}
@@ -410,3 +1418,114 @@ func TestDelete(t *testing.T) {
 		})
 	}
 }
+
+func TestImageContent(t *testing.T) {
+	dir, outside := t.TempDir(), t.TempDir()
+	for _, d := range []string{dir, outside} {
+		if err := os.WriteFile(filepath.Join(d, "front.png"), frontContent, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("front.png", filepath.Join(dir, "inside.png")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "front.png"), filepath.Join(dir, "outside.png")); err != nil {
+		t.Fatal(err)
+	}
+	_, errEscaped := filepath.EvalSymlinks(filepath.Join(dir, filepath.Base(dir), "front.png"))
+	secretRef := &xpv1.SecretKeySelector{
+		SecretReference: xpv1.SecretReference{Namespace: "images", Name: "images"},
+		Key:             "front.png",
+	}
+
+	type want struct {
+		content []byte
+		err     error
+	}
+
+	cases := map[string]struct {
+		reason                string
+		imageDir              string
+		imageSourceNamespaces []string
+		src                   v1alpha1.PetImageSource
+		want                  want
+	}{
+		"ConfigMap": {
+			reason:                "The content should be read from the binaryData of a ConfigMap of an image source namespace.",
+			imageSourceNamespaces: []string{"default"},
+			src:                   frontImage.Source,
+			want:                  want{content: frontContent},
+		},
+		"ConfigMapNamespaceNotAllowed": {
+			reason: "ConfigMaps should not be read from namespaces that are not image source namespaces.",
+			src:    frontImage.Source,
+			want:   want{err: errors.Errorf(errFmtImageNamespace, "ConfigMap", "default")},
+		},
+		"ConfigMapTextData": {
+			reason:                "The data of a ConfigMap should not be read as image content.",
+			imageSourceNamespaces: []string{"default"},
+			src: v1alpha1.PetImageSource{
+				ConfigMapKeyRef: &apisv1alpha1.ConfigMapKeySelector{Namespace: "default", Name: "images", Key: "front.txt"},
+			},
+			want: want{err: errors.Errorf(errFmtKeyNotFound, "front.txt", "binaryData of ConfigMap", "default", "images")},
+		},
+		"Path": {
+			reason:   "The content should be read from the path within the image directory.",
+			imageDir: dir,
+			src:      v1alpha1.PetImageSource{Path: "front.png"},
+			want:     want{content: frontContent},
+		},
+		"PathOutsideImageDirectory": {
+			reason:   "A path should not escape the image directory.",
+			imageDir: dir,
+			src:      v1alpha1.PetImageSource{Path: "../" + filepath.Base(dir) + "/front.png"},
+			want:     want{err: errors.Wrap(errEscaped, errReadImage)},
+		},
+		"SymlinkInside": {
+			reason:   "A symbolic link to a file within the image directory should be followed.",
+			imageDir: dir,
+			src:      v1alpha1.PetImageSource{Path: "inside.png"},
+			want:     want{content: frontContent},
+		},
+		"SymlinkOutside": {
+			reason:   "A symbolic link should not lead out of the image directory.",
+			imageDir: dir,
+			src:      v1alpha1.PetImageSource{Path: "outside.png"},
+			want:     want{err: errors.New(errImageOutside)},
+		},
+		"Secret": {
+			reason:                "The content should be read from a Secret of an image source namespace.",
+			imageSourceNamespaces: []string{"default", "images"},
+			src:                   v1alpha1.PetImageSource{SecretKeyRef: secretRef},
+			want:                  want{content: frontContent},
+		},
+		"SecretNamespaceNotAllowed": {
+			reason:                "Secrets should not be read from namespaces that are not image source namespaces.",
+			imageSourceNamespaces: []string{"default"},
+			src:                   v1alpha1.PetImageSource{SecretKeyRef: secretRef},
+			want:                  want{err: errors.Errorf(errFmtImageNamespace, "Secret", "images")},
+		},
+		"NoImageDirectory": {
+			reason: "Paths should be refused when the ProviderConfig sets no image directory.",
+			src:    v1alpha1.PetImageSource{Path: "front.png"},
+			want:   want{err: errors.New(errNoImageDirectory)},
+		},
+		"NoSource": {
+			reason: "An image without source should be an error.",
+			want:   want{err: errors.New(errNoImageSource)},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := external{kube: imagesKube, imageDir: tc.imageDir, imageSourceNamespaces: tc.imageSourceNamespaces}
+			content, err := e.imageContent(context.Background(), tc.src)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.imageContent(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.content, content); diff != "" {
+				t.Errorf("\n%s\ne.imageContent(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
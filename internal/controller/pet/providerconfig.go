@@ -0,0 +1,308 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pet
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	"github.com/alexisries/provider-petstore/apis/store/v1alpha1"
+	apisv1alpha1 "github.com/alexisries/provider-petstore/apis/v1alpha1"
+	petstore "github.com/alexisries/provider-petstore/internal/clients"
+	"github.com/alexisries/provider-petstore/internal/clients/metrics"
+)
+
+// clientOptions builds the pet store client options described by the
+// supplied ProviderConfig. Failures to read the credentials or the TLS
+// material and headers it references are reported as a condition of the
+// managed resource.
+func (c *connector) clientOptions(ctx context.Context, cr *v1alpha1.Pet, pc *apisv1alpha1.ProviderConfig) ([]petstore.ConfigOption, error) {
+	apiKey, err := c.apiKey(ctx, pc.Spec.Credentials)
+	if err != nil {
+		cr.SetConditions(v1alpha1.StoreAPIFailed(v1alpha1.ReasonCredentialsUnavailable, err))
+		return nil, err
+	}
+
+	to := transportOptions(pc.Spec.Transport)
+	if to.TLS, err = c.tlsOptions(ctx, pc.Spec.TLS); err != nil {
+		cr.SetConditions(v1alpha1.StoreAPIFailed(v1alpha1.ReasonTLSError, err))
+		return nil, err
+	}
+	to.Proxy = proxyOptions(pc.Spec.Proxy)
+	hc, err := c.httpClients.Get(pc.GetName(), pc.GetGeneration(), to)
+	if err != nil {
+		reason := v1alpha1.ReasonTLSError
+		if petstore.IsErrorProxy(err) {
+			reason = v1alpha1.ReasonInvalidProviderConfig
+		}
+		cr.SetConditions(v1alpha1.StoreAPIFailed(reason, err))
+		return nil, errors.Wrap(err, errNewHTTPClient)
+	}
+
+	headers, secrets, err := c.headers(ctx, pc.Spec.Headers)
+	if err != nil {
+		cr.SetConditions(v1alpha1.StoreAPIFailed(v1alpha1.ReasonCredentialsUnavailable, err))
+		return nil, err
+	}
+
+	opts := []petstore.ConfigOption{
+		petstore.WithHTTPClient(hc),
+		petstore.WithAPIKey(apiKey),
+		petstore.WithHeaders(headers),
+		petstore.WithRedactedValues(secrets...),
+		petstore.WithRetryPolicy(retryPolicy(pc.Spec.Retry)),
+		petstore.WithRequestObserver(metrics.Observer(pc.GetName())),
+		petstore.WithLimiter(c.limiters.Get(pc.GetName(), rateLimitOptions(pc.Spec.RateLimit))),
+		petstore.WithResponseCache(c.caches.Get(pc.GetName(), cacheTTL(pc.Spec.ObservationCache))),
+		petstore.WithFormat(petstore.Format(pc.Spec.Format)),
+	}
+	opts = append(opts, decodingOptions(pc.Spec.Decoding, c.unknownFieldsNotifier(cr))...)
+
+	if pc.Spec.OAuth2 != nil {
+		ts, err := c.tokenSource(ctx, pc, hc)
+		if err != nil {
+			cr.SetConditions(v1alpha1.StoreAPIFailed(v1alpha1.ReasonCredentialsUnavailable, err))
+			return nil, err
+		}
+		opts = append(opts, petstore.WithTokenSource(ts))
+	}
+	return opts, nil
+}
+
+// apiKey extracts the pet store API key from the credentials of a
+// ProviderConfig. No key is used when the credentials source is None.
+func (c *connector) apiKey(ctx context.Context, cd apisv1alpha1.ProviderCredentials) (string, error) {
+	data, err := resource.CommonCredentialExtractor(ctx, cd.Source, c.kube, cd.CommonCredentialSelectors)
+	if err != nil {
+		return "", errors.Wrap(err, errGetCreds)
+	}
+	if cd.Source == xpv1.CredentialsSourceNone {
+		return "", nil
+	}
+	key := strings.TrimSpace(string(data))
+	if key == "" {
+		return "", errors.Wrap(errors.New(errEmptyCreds), errGetCreds)
+	}
+	return key, nil
+}
+
+// tokenSource returns the OAuth2 token source of the supplied ProviderConfig.
+// Token sources are cached, so access tokens survive across reconciles.
+func (c *connector) tokenSource(ctx context.Context, pc *apisv1alpha1.ProviderConfig, hc *http.Client) (*petstore.TokenSource, error) {
+	o := pc.Spec.OAuth2
+	ref := o.ClientSecretRef
+	secret, err := resource.CommonCredentialExtractor(ctx, xpv1.CredentialsSourceSecret, c.kube, xpv1.CommonCredentialSelectors{SecretRef: &ref})
+	if err != nil {
+		return nil, errors.Wrap(err, errGetClientSecret)
+	}
+	return c.tokenSources.Get(pc.GetName(), petstore.OAuth2Options{
+		TokenURL:     o.TokenURL,
+		ClientID:     o.ClientID,
+		ClientSecret: strings.TrimSpace(string(secret)),
+		Scopes:       o.Scopes,
+	}, hc), nil
+}
+
+// tlsOptions reads the TLS material referenced by a ProviderConfig.
+func (c *connector) tlsOptions(ctx context.Context, t *apisv1alpha1.TLSConfig) (petstore.TLSOptions, error) {
+	o := petstore.TLSOptions{}
+	if t == nil {
+		return o, nil
+	}
+	o.ServerName = t.ServerName
+	for _, s := range []struct {
+		from *apisv1alpha1.KeySelector
+		to   *[]byte
+	}{
+		{from: t.CABundle, to: &o.CABundle},
+		{from: t.ClientCert, to: &o.ClientCert},
+		{from: t.ClientKey, to: &o.ClientKey},
+	} {
+		if s.from == nil {
+			continue
+		}
+		v, err := resolveKey(ctx, c.kube, s.from)
+		if err != nil {
+			return o, errors.Wrap(err, errGetTLS)
+		}
+		*s.to = v
+	}
+	return o, nil
+}
+
+// headers resolves the static headers of a ProviderConfig, reading the values
+// held in Secrets. The values read from Secrets are also returned on their
+// own so that they can be kept out of logs.
+func (c *connector) headers(ctx context.Context, hs map[string]apisv1alpha1.HeaderValue) (map[string]string, []string, error) {
+	h := make(map[string]string, len(hs))
+	var secrets []string
+	for name, hv := range hs {
+		if hv.SecretKeyRef == nil {
+			h[name] = hv.Value
+			continue
+		}
+		v, err := resolveKey(ctx, c.kube, &apisv1alpha1.KeySelector{SecretKeyRef: hv.SecretKeyRef})
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, errFmtGetHeader, name)
+		}
+		h[name] = strings.TrimSpace(string(v))
+		secrets = append(secrets, h[name])
+	}
+	return h, secrets, nil
+}
+
+// resolveKey returns the value of the key of a Secret or ConfigMap selected
+// by the supplied KeySelector.
+func resolveKey(ctx context.Context, kube client.Client, ks *apisv1alpha1.KeySelector) ([]byte, error) {
+	switch {
+	case ks.SecretKeyRef != nil:
+		ref := ks.SecretKeyRef
+		s := &corev1.Secret{}
+		if err := kube.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, s); err != nil {
+			return nil, errors.Wrap(err, errGetSecret)
+		}
+		v, ok := s.Data[ref.Key]
+		if !ok {
+			return nil, errors.Errorf(errFmtKeyNotFound, ref.Key, "Secret", ref.Namespace, ref.Name)
+		}
+		return v, nil
+	case ks.ConfigMapKeyRef != nil:
+		ref := ks.ConfigMapKeyRef
+		cm := &corev1.ConfigMap{}
+		if err := kube.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, cm); err != nil {
+			return nil, errors.Wrap(err, errGetConfigMap)
+		}
+		if v, ok := cm.Data[ref.Key]; ok {
+			return []byte(v), nil
+		}
+		if v, ok := cm.BinaryData[ref.Key]; ok {
+			return v, nil
+		}
+		return nil, errors.Errorf(errFmtKeyNotFound, ref.Key, "ConfigMap", ref.Namespace, ref.Name)
+	}
+	return nil, errors.New(errNoKeySource)
+}
+
+// transportOptions converts the transport settings of a ProviderConfig into
+// options understood by the pet store client.
+func transportOptions(t *apisv1alpha1.TransportConfig) petstore.TransportOptions {
+	o := petstore.TransportOptions{}
+	if t == nil {
+		return o
+	}
+	o.RequestTimeout = durationValue(t.RequestTimeout)
+	o.IdleConnTimeout = durationValue(t.IdleConnTimeout)
+	o.KeepAlive = durationValue(t.KeepAlive)
+	if t.MaxIdleConns != nil {
+		o.MaxIdleConns = *t.MaxIdleConns
+	}
+	if t.MaxConnsPerHost != nil {
+		o.MaxConnsPerHost = *t.MaxConnsPerHost
+	}
+	return o
+}
+
+// rateLimitOptions converts the rate limits of a ProviderConfig into options
+// understood by the pet store client.
+func rateLimitOptions(r *apisv1alpha1.RateLimitConfig) petstore.RateLimitOptions {
+	o := petstore.RateLimitOptions{}
+	if r == nil {
+		return o
+	}
+	if r.RequestsPerSecond != nil {
+		o.RequestsPerSecond = float64(*r.RequestsPerSecond)
+	}
+	if r.Burst != nil {
+		o.Burst = *r.Burst
+	}
+	if r.MaxInFlight != nil {
+		o.MaxInFlight = *r.MaxInFlight
+	}
+	return o
+}
+
+// decodingOptions converts the decoding settings of a ProviderConfig into
+// options of the pet store client. In strict mode unknown fields are passed
+// to the supplied notifier.
+func decodingOptions(d *apisv1alpha1.DecodingConfig, notify petstore.UnknownFieldsNotifier) []petstore.ConfigOption {
+	if d == nil {
+		return nil
+	}
+	var opts []petstore.ConfigOption
+	if d.MaxResponseBytes != nil {
+		opts = append(opts, petstore.WithMaxResponseSize(*d.MaxResponseBytes))
+	}
+	if d.Strict {
+		opts = append(opts, petstore.WithStrictDecoding(notify))
+	}
+	return opts
+}
+
+// proxyOptions converts the proxy settings of a ProviderConfig into options
+// understood by the pet store client.
+func proxyOptions(p *apisv1alpha1.ProxyConfig) petstore.ProxyOptions {
+	if p == nil {
+		return petstore.ProxyOptions{}
+	}
+	return petstore.ProxyOptions{URL: p.URL, NoProxy: p.NoProxy}
+}
+
+// cacheTTL returns how long observed pets are cached for the supplied
+// ProviderConfig.
+func cacheTTL(o *apisv1alpha1.ObservationCacheConfig) time.Duration {
+	if o == nil {
+		return 0
+	}
+	return durationValue(o.TTL)
+}
+
+func durationValue(d *metav1.Duration) time.Duration {
+	if d == nil {
+		return 0
+	}
+	return d.Duration
+}
+
+// retryPolicy converts the retry settings of a ProviderConfig into a policy
+// understood by the pet store client.
+func retryPolicy(r *apisv1alpha1.RetryPolicy) petstore.RetryPolicy {
+	p := petstore.RetryPolicy{}
+	if r == nil {
+		return p
+	}
+	if r.MaxRetries != nil {
+		p.MaxRetries = *r.MaxRetries
+		if p.MaxRetries == 0 {
+			// The client treats zero as "use the default".
+			p.MaxRetries = -1
+		}
+	}
+	p.InitialBackoff = durationValue(r.InitialBackoff)
+	p.MaxBackoff = durationValue(r.MaxBackoff)
+	return p
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pet
+
+import (
+	"context"
+	"testing"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apisv1alpha1 "github.com/alexisries/provider-petstore/apis/v1alpha1"
+)
+
+func TestResolveKey(t *testing.T) {
+	kube := &test.MockClient{MockGet: func(_ context.Context, key client.ObjectKey, obj client.Object) error {
+		if key.Name != "petstore" {
+			return errBoom
+		}
+		switch o := obj.(type) {
+		case *corev1.Secret:
+			o.Data = map[string][]byte{"key": []byte("s3cret")}
+		case *corev1.ConfigMap:
+			o.Data = map[string]string{"ca.crt": "data"}
+			o.BinaryData = map[string][]byte{"ca.der": []byte("binary")}
+		}
+		return nil
+	}}
+	secretRef := func(name, key string) *xpv1.SecretKeySelector {
+		return &xpv1.SecretKeySelector{SecretReference: xpv1.SecretReference{Namespace: "ns", Name: name}, Key: key}
+	}
+	configMapRef := func(name, key string) *apisv1alpha1.ConfigMapKeySelector {
+		return &apisv1alpha1.ConfigMapKeySelector{Namespace: "ns", Name: name, Key: key}
+	}
+
+	type want struct {
+		value []byte
+		err   error
+	}
+
+	cases := map[string]struct {
+		reason string
+		ks     apisv1alpha1.KeySelector
+		want   want
+	}{
+		"Secret": {
+			reason: "The value should be read from the data of the Secret.",
+			ks:     apisv1alpha1.KeySelector{SecretKeyRef: secretRef("petstore", "key")},
+			want:   want{value: []byte("s3cret")},
+		},
+		"SecretNotFound": {
+			reason: "A Secret that cannot be read should be an error.",
+			ks:     apisv1alpha1.KeySelector{SecretKeyRef: secretRef("other", "key")},
+			want:   want{err: errors.Wrap(errBoom, errGetSecret)},
+		},
+		"SecretKeyNotFound": {
+			reason: "A key missing from the Secret should be an error.",
+			ks:     apisv1alpha1.KeySelector{SecretKeyRef: secretRef("petstore", "other")},
+			want:   want{err: errors.Errorf(errFmtKeyNotFound, "other", "Secret", "ns", "petstore")},
+		},
+		"ConfigMapData": {
+			reason: "The value should be read from the data of the ConfigMap.",
+			ks:     apisv1alpha1.KeySelector{ConfigMapKeyRef: configMapRef("petstore", "ca.crt")},
+			want:   want{value: []byte("data")},
+		},
+		"ConfigMapBinaryData": {
+			reason: "The value should be read from the binaryData of the ConfigMap.",
+			ks:     apisv1alpha1.KeySelector{ConfigMapKeyRef: configMapRef("petstore", "ca.der")},
+			want:   want{value: []byte("binary")},
+		},
+		"ConfigMapNotFound": {
+			reason: "A ConfigMap that cannot be read should be an error.",
+			ks:     apisv1alpha1.KeySelector{ConfigMapKeyRef: configMapRef("other", "ca.crt")},
+			want:   want{err: errors.Wrap(errBoom, errGetConfigMap)},
+		},
+		"ConfigMapKeyNotFound": {
+			reason: "A key missing from the ConfigMap should be an error.",
+			ks:     apisv1alpha1.KeySelector{ConfigMapKeyRef: configMapRef("petstore", "other")},
+			want:   want{err: errors.Errorf(errFmtKeyNotFound, "other", "ConfigMap", "ns", "petstore")},
+		},
+		"NoSource": {
+			reason: "A selector selecting nothing should be an error.",
+			want:   want{err: errors.New(errNoKeySource)},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := resolveKey(context.Background(), kube, &tc.ks)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nresolveKey(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.value, got); diff != "" {
+				t.Errorf("\n%s\nresolveKey(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
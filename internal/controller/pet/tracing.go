@@ -0,0 +1,123 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pet
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+const tracerName = "github.com/alexisries/provider-petstore/internal/controller/pet"
+
+// A tracingReconciler records a span around every reconcile, so that the
+// spans of the external client and of the requests it sends to the pet store
+// end up in a single trace per reconcile.
+type tracingReconciler struct {
+	reconcile.Reconciler
+}
+
+func (r *tracingReconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "Reconcile", trace.WithAttributes(attribute.String("name", req.Name)))
+	res, err := r.Reconciler.Reconcile(ctx, req)
+	endSpan(span, err)
+	return res, err
+}
+
+// A tracingConnector records a span around Connect, which includes looking up
+// the ProviderConfig and reading its credentials.
+type tracingConnector struct {
+	managed.ExternalConnecter
+}
+
+func (c *tracingConnector) Connect(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
+	ctx, span := startSpan(ctx, "Connect", mg)
+	ext, err := c.ExternalConnecter.Connect(ctx, mg)
+	endSpan(span, err)
+	if err != nil {
+		return nil, err
+	}
+	return &tracingExternal{ExternalClient: ext}, nil
+}
+
+// A tracingExternal records a span around each operation of an
+// ExternalClient. The requests it sends to the pet store are recorded as
+// child spans.
+type tracingExternal struct {
+	managed.ExternalClient
+}
+
+func (e *tracingExternal) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
+	ctx, span := startSpan(ctx, "Observe", mg)
+	o, err := e.ExternalClient.Observe(ctx, mg)
+	span.SetAttributes(
+		attribute.Bool("resource.exists", o.ResourceExists),
+		attribute.Bool("resource.up_to_date", o.ResourceUpToDate),
+	)
+	endSpan(span, err)
+	return o, err
+}
+
+func (e *tracingExternal) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
+	ctx, span := startSpan(ctx, "Create", mg)
+	c, err := e.ExternalClient.Create(ctx, mg)
+	endSpan(span, err)
+	return c, err
+}
+
+func (e *tracingExternal) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
+	ctx, span := startSpan(ctx, "Update", mg)
+	u, err := e.ExternalClient.Update(ctx, mg)
+	endSpan(span, err)
+	return u, err
+}
+
+func (e *tracingExternal) Delete(ctx context.Context, mg resource.Managed) error {
+	ctx, span := startSpan(ctx, "Delete", mg)
+	err := e.ExternalClient.Delete(ctx, mg)
+	endSpan(span, err)
+	return err
+}
+
+func startSpan(ctx context.Context, name string, mg resource.Managed) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(
+		attribute.String("name", mg.GetName()),
+		attribute.String("provider_config", providerConfigName(mg)),
+	))
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func providerConfigName(mg resource.Managed) string {
+	if ref := mg.GetProviderConfigReference(); ref != nil {
+		return ref.Name
+	}
+	return ""
+}